@@ -0,0 +1,144 @@
+package main
+
+import (
+	"debug/elf"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// what the object file or the executable of a result contains
+type BinaryStats struct {
+	File              string `json:"file" xml:"file,attr"` // relative to the directory of the test set
+	Text              uint64 `json:"text" xml:"text,attr"` // the sizes of the sections, as counted by size(1)
+	Data              uint64 `json:"data" xml:"data,attr"`
+	BSS               uint64 `json:"bss" xml:"bss,attr"`
+	Symbols           int    `json:"symbols" xml:"symbols,attr"`
+	ExternSymbols     int    `json:"externSymbols" xml:"externSymbols,attr"` // the global symbols defined by it
+	LongestSymbol     int    `json:"longestSymbol" xml:"longestSymbol,attr"`
+	LongestSymbolName string `json:"longestSymbolName" xml:"longestSymbolName,attr"` // shortened if it is too long
+	VtableSize        uint64 `json:"vtableSize" xml:"vtableSize,attr"`               // the sum of the sizes of the vtables
+	Relocations       int    `json:"relocations" xml:"relocations,attr"`
+	SymbolCheck       string `json:"symbolCheck,omitempty" xml:"symbolCheck,omitempty"` // why the symbols are not the expected ones
+}
+
+// the longest symbol name kept in the results, the others are shortened
+const maxSymbolNameLength = 80
+
+// the map which maps the name of a test case to a function checking the symbols of its object file or executable
+// for a given count and size of the pointers, returns why they are not the expected ones or an empty string
+var expectedSymbols = map[string]func(n, pointerSize int, symbols []elf.Symbol) string{
+	"externIdentifiersInOneTranslationUnit": func(n, pointerSize int, symbols []elf.Symbol) string {
+		return expectCount(n, "extern variables v0..v"+strconv.Itoa(n-1), countSymbols(symbols, externVariable))
+	},
+	"externIdentifierNameLength": func(n, pointerSize int, symbols []elf.Symbol) string {
+		for _, s := range symbols {
+			if externVariable.MatchString(s.Name) && len(s.Name) == n && isGlobalDefinition(s) {
+				return ""
+			}
+		}
+		return "no extern variable with a name of " + strconv.Itoa(n) + " characters"
+	},
+	"finalOverridingVirtualFunctions": func(n, pointerSize int, symbols []elf.Symbol) string {
+		// the vtable is only checked if it is emitted, the optimizer can devirtualize all the calls
+		for _, s := range symbols {
+			if s.Name == "_ZTV7Derived" && s.Section != elf.SHN_UNDEF {
+				// the primary and the secondary vtables of Derived hold a pointer for each of its final overriders
+				entries := s.Size / uint64(pointerSize)
+				if entries < uint64(classHierarchySize(n)) {
+					return "the vtable of Derived has " + strconv.FormatUint(entries, 10) + " entries, expected " +
+						strconv.Itoa(classHierarchySize(n)) + " at least"
+				}
+				return ""
+			}
+		}
+		return ""
+	},
+}
+
+// the extern variables generated by the tests, v followed by a number or by letters
+var externVariable = regexp.MustCompile(`^v[0-9a-z]*$`)
+
+// the number of global symbols defined by the binary whose name matches the expression
+func countSymbols(symbols []elf.Symbol, name *regexp.Regexp) int {
+	n := 0
+	for _, s := range symbols {
+		if isGlobalDefinition(s) && name.MatchString(s.Name) {
+			n++
+		}
+	}
+	return n
+}
+
+// whether the symbol is a global one defined by the binary
+func isGlobalDefinition(s elf.Symbol) bool {
+	return elf.ST_BIND(s.Info) == elf.STB_GLOBAL && s.Section != elf.SHN_UNDEF
+}
+
+// an empty string if the count is the expected one, otherwise the difference
+func expectCount(expected int, what string, actual int) string {
+	if actual == expected {
+		return ""
+	}
+	return strconv.Itoa(actual) + " " + what + " found, expected " + strconv.Itoa(expected)
+}
+
+// analyzes the ELF object file or executable, nil if it is not one. The symbols are checked against the expectations
+// of the test for the count
+func analyzeBinary(fileName, displayName, testName, count string) *BinaryStats {
+	f, err := elf.Open(fileName)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	stats := &BinaryStats{File: displayName}
+	for _, section := range f.Sections {
+		switch {
+		case section.Type == elf.SHT_REL || section.Type == elf.SHT_RELA:
+			if section.Entsize > 0 {
+				stats.Relocations += int(section.Size / section.Entsize)
+			}
+		case section.Flags&elf.SHF_ALLOC == 0:
+		case section.Type == elf.SHT_NOBITS:
+			stats.BSS += section.Size
+		case section.Flags&elf.SHF_WRITE != 0:
+			stats.Data += section.Size
+		default:
+			stats.Text += section.Size
+		}
+	}
+
+	symbols, err := f.Symbols()
+	if err != nil {
+		// a stripped executable only has the dynamic symbols
+		symbols, _ = f.DynamicSymbols()
+	}
+	stats.Symbols = len(symbols)
+	for _, s := range symbols {
+		if isGlobalDefinition(s) {
+			stats.ExternSymbols++
+		}
+		if len(s.Name) > stats.LongestSymbol {
+			stats.LongestSymbol = len(s.Name)
+			stats.LongestSymbolName = s.Name
+		}
+		if strings.HasPrefix(s.Name, "_ZTV") {
+			stats.VtableSize += s.Size
+		}
+	}
+	if len(stats.LongestSymbolName) > maxSymbolNameLength {
+		stats.LongestSymbolName = stats.LongestSymbolName[:maxSymbolNameLength] + "..."
+	}
+
+	if expected, ok := expectedSymbols[testName]; ok {
+		if n, err := strconv.Atoi(count); err == nil {
+			pointerSize := 8
+			if f.Class == elf.ELFCLASS32 {
+				pointerSize = 4
+			}
+			stats.SymbolCheck = expected(n, pointerSize, symbols)
+		}
+	}
+	return stats
+}
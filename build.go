@@ -0,0 +1,215 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// the kinds of the translation units a test consists of besides its main source file
+const (
+	sourceUnit  = "source"  // compiled and linked with the main source file
+	libraryUnit = "library" // compiled into an object file archived into a static library linked with the test
+	moduleUnit  = "module"  // a module interface unit, compiled into its BMI and an object file before the units importing it
+)
+
+// a translation unit of a test besides its main source file
+type translationUnit struct {
+	fileName string // relative to the directory of the test set
+	kind     string
+	module   string // the name of the module or partition (ie. m:p) of a module unit
+}
+
+// writes a translation unit of the test for the given count besides its main source file, the units of a test being
+// numbered by index. Returns the unit, the generators of the tests made of several units return them with their main
+// source file
+func writeUnitFile(funName, count string, index int, kind, content string) translationUnit {
+	fileName := writeTestFile(funName, count+"-"+strconv.Itoa(index), content)
+	return translationUnit{fileName: filepath.Base(fileName), kind: kind}
+}
+
+// writes the interface unit of a module (or of a partition) of the test, the module units being compiled in the order
+// they are returned by the generator, so a partition has to come before the primary interface of its module
+func writeModuleFile(funName, count string, index int, module, content string) translationUnit {
+	fileName := writeTestFile(funName, count+"-"+strconv.Itoa(index), content)
+	return translationUnit{fileName: filepath.Base(fileName), kind: moduleUnit, module: module}
+}
+
+// the object file of a module unit compiled by a compiler of the family, named after its BMI the way the compilers
+// name them (the partition p of the module m is m-p), so clang writes the BMI next to it where the units importing it
+// look for it
+func (u translationUnit) object(familyName string) string {
+	return phaseOutputOf(familyName, u.bmiName(), interfacePhase)
+}
+
+// the name of the BMI of a module unit, without its extension
+func (u translationUnit) bmiName() string {
+	return strings.ReplaceAll(u.module, ":", "-")
+}
+
+// the flags naming the BMI of a module unit for the compilers which do not name it after the module, empty for the
+// others
+func (u translationUnit) bmiFlags(familyName string) string {
+	if f := familyOf(familyName); len(f.bmiOutputFlag) > 0 {
+		return f.bmiOutputFlag + u.bmiName() + f.bmiExtension
+	}
+	return ""
+}
+
+// the main source file of the test followed by the files of its translation units of the given kind, or of all of
+// them if the kind is empty
+func (t generatedTest) sources(kind string) []string {
+	sources := []string{t.fileName}
+	for _, u := range t.units {
+		if len(kind) == 0 || u.kind == kind {
+			sources = append(sources, u.fileName)
+		}
+	}
+	return sources
+}
+
+// the files of the translation units of the test of the given kind
+func (t generatedTest) unitFiles(kind string) []string {
+	return t.sources(kind)[1:]
+}
+
+// the main source file of the test followed by the files of its translation units besides the module units, the ones
+// run through the phases once the modules are compiled
+func (t generatedTest) importingSources() []string {
+	sources := []string{t.fileName}
+	for _, u := range t.units {
+		if u.kind != moduleUnit {
+			sources = append(sources, u.fileName)
+		}
+	}
+	return sources
+}
+
+// the module units of the test, in the order they are compiled
+func (t generatedTest) modules() []translationUnit {
+	modules := make([]translationUnit, 0)
+	for _, u := range t.units {
+		if u.kind == moduleUnit {
+			modules = append(modules, u)
+		}
+	}
+	return modules
+}
+
+// the object files of the module units of the test compiled by a compiler of the family, linked with the other units
+func (t generatedTest) moduleObjects(familyName string) []string {
+	objects := make([]string, 0)
+	for _, u := range t.modules() {
+		objects = append(objects, u.object(familyName))
+	}
+	return objects
+}
+
+// the flags compiling the units of the test with a compiler of the family if it consists of modules, empty otherwise
+func moduleFlags(familyName string, t generatedTest) string {
+	if len(familyName) == 0 || len(t.modules()) == 0 {
+		return ""
+	}
+	return familyOf(familyName).moduleFlags
+}
+
+// the static library the library units of the test are archived into by a compiler of the family
+func (t generatedTest) libraryName(familyName string) string {
+	return t.name + familyOf(familyName).libraryExtension
+}
+
+// a process run while building a test
+type buildStep struct {
+	args     []string
+	compiles bool // whether the compiler runs, so the flags measuring its phases apply to it
+}
+
+// the processes building the test with the tool, in the order they have to be run. A test made of one source file
+// is built by one command. The module units of a compiler are compiled first in every phase, since the other units
+// cannot even be preprocessed without their BMIs. The phases of the translation units of a bigger test are run one
+// unit after the other, and the full build compiles the library units, archives them, then links the executable from
+// the other units, the module objects and the library
+func buildSteps(tool ToolEntry, t generatedTest, mode, phase, std string, debugInfo bool) []buildStep {
+	compiler, familyName := toolCompiler(tool)
+	if len(t.units) == 0 {
+		return []buildStep{{expandCommand(tool, t, mode, phase, std, debugInfo), len(compiler) > 0}}
+	}
+
+	steps := make([]buildStep, 0)
+	sources := t.sources("")
+	if len(compiler) > 0 {
+		for _, u := range t.modules() {
+			args := expandCommandFor(tool, t, mode, interfacePhase, std, debugInfo, []string{u.fileName}, u.object(familyName))
+			steps = append(steps, buildStep{append(args, strings.Fields(u.bmiFlags(familyName))...), true})
+		}
+		sources = t.importingSources()
+	}
+	if len(compiler) == 0 || phase != linkPhase {
+		for _, source := range sources {
+			output := phaseOutputOf(familyName, strings.TrimSuffix(source, filepath.Ext(source)), phase)
+			steps = append(steps, buildStep{expandCommandFor(tool, t, mode, phase, std, debugInfo, []string{source}, output), len(compiler) > 0})
+		}
+		return steps
+	}
+
+	sources = append(t.sources(sourceUnit), t.moduleObjects(familyName)...)
+	if objects := libraryObjects(familyName, t); len(objects) > 0 {
+		for i, source := range t.unitFiles(libraryUnit) {
+			steps = append(steps, buildStep{expandCommandFor(tool, t, mode, compilePhase, std, debugInfo, []string{source}, objects[i]), true})
+		}
+		steps = append(steps, buildStep{archiveArgs(familyName, t.libraryName(familyName), objects), false})
+		sources = append(sources, t.libraryName(familyName))
+	}
+	return append(steps, buildStep{expandCommandFor(tool, t, mode, phase, std, debugInfo, sources, phaseOutput(familyName, t, phase)), true})
+}
+
+// the command archiving the object files into a static library with the archiver of the family
+func archiveArgs(familyName, library string, objects []string) []string {
+	return append(strings.Fields(familyOf(familyName).archiver+library), objects...)
+}
+
+// the object files of the library units of the test compiled by a compiler of the family
+func libraryObjects(familyName string, t generatedTest) []string {
+	objects := make([]string, 0)
+	for _, source := range t.unitFiles(libraryUnit) {
+		objects = append(objects, phaseOutputOf(familyName, strings.TrimSuffix(source, filepath.Ext(source)), compilePhase))
+	}
+	return objects
+}
+
+// the rules of the Makefile compiling the library units of the test and archiving them into its static library
+func makefileLibraryRules(t generatedTest) string {
+	objects := libraryObjects(familyName(), t)
+	library := t.libraryName(familyName())
+	rules := library + ": " + strings.Join(objects, " ") + "\n\t" +
+		strings.Join(archiveArgs(familyName(), library, objects), " ") + "\n\n"
+	for i, source := range t.unitFiles(libraryUnit) {
+		rules += objects[i] + ": " + source + "\n\t" + makeCompileCommand(t) + phaseFlag(familyName(), compilePhase) + " " +
+			outputFlagOf(familyName(), compilePhase) + objects[i] + " " + source + "\n\n"
+	}
+	return rules
+}
+
+// the rules of the Makefile compiling the module units of the test, each one after the previous one so that the BMIs
+// of the partitions exist when the primary interface of their module is compiled
+func makefileModuleRules(t generatedTest) string {
+	rules := ""
+	previous := ""
+	for _, u := range t.modules() {
+		object := u.object(familyName())
+		rules += object + ": " + u.fileName + previous + "\n\t" + makeCompileCommand(t) +
+			phaseFlag(familyName(), interfacePhase) + " " + outputFlagOf(familyName(), interfacePhase) + object + " " +
+			strings.TrimSpace(u.fileName+" "+u.bmiFlags(familyName())) + "\n\n"
+		previous = " " + object
+	}
+	return rules
+}
+
+// the command line of the steps, as shown in the results
+func commandLine(steps []buildStep) string {
+	commands := make([]string, 0, len(steps))
+	for _, step := range steps {
+		commands = append(commands, strings.Join(step.args, " "))
+	}
+	return strings.Join(commands, " && ")
+}
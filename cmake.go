@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+)
+
+// the label of the tests in CTest stressing the compilers beyond the limits listed by the standard
+const stressLabel = "stress"
+
+// creates the content of the CMakeLists.txt for the generated tests, registering each of them with CTest
+func cmakeListsContent(tests []generatedTest) string {
+	// the module units are built with the file sets of CMake 3.28, which scans them for their dependencies
+	version := "3.10"
+	for _, t := range tests {
+		if len(t.modules()) > 0 {
+			version = "3.28"
+		}
+	}
+	content := "cmake_minimum_required(VERSION " + version + ")\n\n" + "project(" + testSet.SetName + " " + cmakeLanguage() + ")\n\nenable_testing()\n\n"
+	raise := "OFF"
+	if buildFilesRaiseLimits() {
+		raise = "ON"
+	}
+	content += "option(" + raisedLimitsSwitch + " \"compile the tests with the flags raising the limits they stress\" " + raise + ")\n\n"
+
+	for _, t := range tests {
+		flags := strings.TrimSpace(familyFlags() + " " + t.entry.CompilerFlags)
+		raising := raisingFlags(familyName(), t.entry.TestName, t.count)
+
+		content += "# " + t.name + "\n"
+		content += "add_executable(" + t.name + " " + strings.Join(t.sources(sourceUnit), " ") + ")\n"
+		content += cmakeCompileOptions(t.name, flags, raising)
+		if modules := t.unitFiles(moduleUnit); len(modules) > 0 {
+			content += "target_sources(" + t.name + " PRIVATE FILE_SET CXX_MODULES FILES " + strings.Join(modules, " ") + ")\n"
+			content += "target_compile_features(" + t.name + " PRIVATE cxx_std_20)\n"
+		}
+		if library := t.unitFiles(libraryUnit); len(library) > 0 {
+			content += "add_library(" + t.name + "-lib STATIC " + strings.Join(library, " ") + ")\n"
+			content += cmakeCompileOptions(t.name+"-lib", flags, raising)
+			content += "target_link_libraries(" + t.name + " PRIVATE " + t.name + "-lib)\n"
+		}
+		content += "add_test(NAME " + t.name + " COMMAND " + t.name + ")\n"
+
+		// only the tests of a limit listed by the standard (with its clause in the description) get its label
+		labels := []string{stressLabel}
+		if clause := annexClause(t.entry.Description); len(clause) > 0 {
+			labels = []string{standardLabel(), clause}
+		}
+		labels = append(labels, t.entry.TestName)
+
+		content += "set_tests_properties(" + t.name + " PROPERTIES"
+		if expected := expectedOutput(t.entry.TestName, t.count); len(expected) > 0 {
+			content += " PASS_REGULAR_EXPRESSION " + cmakeQuote(expected)
+		}
+		content += " LABELS " + cmakeQuote(strings.Join(labels, ";")) + ")\n\n"
+	}
+
+	return content
+}
+
+// the commands adding the flags to the target, and the flags raising its limits if the option of the raised limits is
+// on
+func cmakeCompileOptions(target, flags, raising string) string {
+	content := ""
+	if len(flags) > 0 {
+		content += "target_compile_options(" + target + " PRIVATE " + strings.Join(strings.Fields(flags), " ") + ")\n"
+	}
+	if len(raising) > 0 {
+		content += "if(" + raisedLimitsSwitch + ")\n  target_compile_options(" + target + " PRIVATE " +
+			strings.Join(strings.Fields(raising), " ") + ")\nendif()\n"
+	}
+	return content
+}
+
+// quotes a string so that it can be used as a quoted argument in a CMake command
+func cmakeQuote(s string) string {
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$")
+	return "\"" + r.Replace(s) + "\""
+}
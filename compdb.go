@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// one entry of a JSON compilation database, see https://clang.llvm.org/docs/JSONCompilationDatabase.html
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Arguments []string `json:"arguments"`
+	Output    string   `json:"output"`
+}
+
+// creates the content of the compile_commands.json for the generated tests, located in dir
+func compileCommandsContent(tests []generatedTest, dir string) string {
+	commands := make([]compileCommand, 0, len(tests))
+	for _, t := range tests {
+		commands = append(commands, compileCommand{
+			Directory: dir,
+			File:      filepath.Join(dir, t.fileName),
+			Arguments: compileArgs(t, linkPhase, t.fileName, t.name),
+			Output:    filepath.Join(dir, t.name),
+		})
+
+		// the other translation units of the test are compiled into object files, the module units into their BMIs too
+		for _, u := range t.units {
+			phase, object := compilePhase, phaseOutputOf(familyName(), strings.TrimSuffix(u.fileName, filepath.Ext(u.fileName)), compilePhase)
+			if u.kind == moduleUnit {
+				phase, object = interfacePhase, u.object(familyName())
+			}
+			args := compileArgs(t, phase, u.fileName, object)
+			args = append(args, strings.Fields(u.bmiFlags(familyName()))...)
+			commands = append(commands, compileCommand{
+				Directory: dir,
+				File:      filepath.Join(dir, u.fileName),
+				Arguments: args,
+				Output:    filepath.Join(dir, object),
+			})
+		}
+	}
+
+	content, err := json.MarshalIndent(commands, "", "  ")
+	check(err)
+	return string(content) + "\n"
+}
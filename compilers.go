@@ -0,0 +1,163 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// the dialect of a family of compilers, used when generating the build files
+type compilerFamily struct {
+	compiler         string // the default executable of the family
+	cCompiler        string // the default executable of the family compiling C
+	flags            string // flags always needed by the family, the ones raising its limits are in limitRaisingFlags
+	cFlags           string // the same when compiling C
+	includeFlag      string // prefix of an include directory
+	outputFlag       string // prefix of the output file, a trailing space means it is a separate argument
+	objectOutputFlag string // the same for the object files, written by the compiler stopped after compiling
+	objectExtension  string
+	debugFlag        string // the flag generating the debug information
+	archiver         string // the command creating a static library, followed by the name of the library
+	libraryExtension string
+	moduleFlags      string // the flags compiling the units of a test made of C++20 modules, finding their BMIs
+	bmiOutputFlag    string // prefix of the BMI of a module unit if it is not named after the module
+	bmiExtension     string // the extension of the BMI named with bmiOutputFlag
+	makefile         bool   // whether the family is used with make (and /usr/bin/time)
+}
+
+// the known compiler families, the key is the value of "compilerFamily" in the json file
+var compilerFamilies = map[string]compilerFamily{
+	"gcc": {compiler: "g++", cCompiler: "gcc", includeFlag: "-I", outputFlag: "-o ", objectOutputFlag: "-o ",
+		objectExtension: ".o", debugFlag: "-g", archiver: "ar rcs ", libraryExtension: ".a", moduleFlags: "-fmodules-ts",
+		makefile: true},
+	"clang": {compiler: "clang++", cCompiler: "clang", includeFlag: "-I", outputFlag: "-o ", objectOutputFlag: "-o ",
+		objectExtension: ".o", debugFlag: "-g", archiver: "ar rcs ", libraryExtension: ".a",
+		moduleFlags: "-fprebuilt-module-path=.", makefile: true},
+	"icc": {compiler: "icpc", cCompiler: "icc", includeFlag: "-I", outputFlag: "-o ", objectOutputFlag: "-o ",
+		objectExtension: ".o", debugFlag: "-g", archiver: "xiar rcs ", libraryExtension: ".a", makefile: true},
+	"msvc": {compiler: "cl", cCompiler: "cl", flags: "/std:c++17", includeFlag: "/I", outputFlag: "/Fe",
+		objectOutputFlag: "/Fo", objectExtension: ".obj", debugFlag: "/Zi", archiver: "lib /OUT:", libraryExtension: ".lib",
+		moduleFlags: "/ifcSearchDir .", bmiOutputFlag: "/ifcOutput ", bmiExtension: ".ifc"},
+}
+
+// guesses the family of a compiler from the name of its executable
+func guessFamily(compiler string) string {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(compiler), ".exe"))
+	switch {
+	case name == "cl" || name == "clang-cl":
+		return "msvc"
+	case strings.Contains(name, "clang"):
+		return "clang"
+	case strings.HasPrefix(name, "icc") || strings.HasPrefix(name, "icpc") || strings.HasPrefix(name, "icpx") || strings.HasPrefix(name, "icx"):
+		return "icc"
+	}
+	return "gcc"
+}
+
+// the name of the family of the compiler of the test set, as declared in the json file or guessed from the compiler
+func familyName() string {
+	if len(testSet.CompilerFamily) > 0 {
+		return testSet.CompilerFamily
+	}
+	return guessFamily(compilerName())
+}
+
+// the family of the compiler of the test set
+func family() compilerFamily {
+	return familyOf(familyName())
+}
+
+// the family with the given name
+func familyOf(name string) compilerFamily {
+	f, ok := compilerFamilies[name]
+	if !ok {
+		panic("unknown compiler family: " + name)
+	}
+	return f
+}
+
+// the compiler of the test set, as declared in the json file or the default one of the declared family for the
+// language of the test set
+func compilerName() string {
+	if len(testSet.Compiler) > 0 {
+		return testSet.Compiler
+	}
+	f, ok := compilerFamilies[testSet.CompilerFamily]
+	if !ok {
+		f = compilerFamilies["gcc"]
+	}
+	if language() == cLanguage {
+		return f.cCompiler
+	}
+	return f.compiler
+}
+
+// the flags all the tests are compiled with, in the dialect of the family
+func familyFlags() string {
+	return familyFlagsOf(familyName())
+}
+
+// the flags all the tests are compiled with by a compiler of the named family, or by any tool if the name is empty
+func familyFlagsOf(name string) string {
+	if len(name) == 0 {
+		return testSet.CompilerFlags
+	}
+	if language() == cLanguage {
+		return strings.TrimSpace(familyOf(name).cFlags + " " + testSet.CompilerFlags)
+	}
+	return strings.TrimSpace(familyOf(name).flags + " " + testSet.CompilerFlags)
+}
+
+// the compiler used by a tool and the name of its family. The compiler is empty if the tool is not a compiler, ie.
+// it neither declares one nor uses the {compiler} placeholder
+func toolCompiler(tool ToolEntry) (string, string) {
+	compiler := tool.Compiler
+	if len(compiler) == 0 {
+		if !strings.Contains(tool.Command, "{compiler}") {
+			return "", ""
+		}
+		compiler = compilerName()
+	}
+
+	switch {
+	case len(tool.CompilerFamily) > 0:
+		return compiler, tool.CompilerFamily
+	case compiler == compilerName():
+		return compiler, familyName()
+	}
+	return compiler, guessFamily(compiler)
+}
+
+// the complete command line compiling source of the test into output with the compiler of the test set, stopping
+// after the phase, with the flags raising its limits if the build files raise them by default
+func compileArgs(t generatedTest, phase, source, output string) []string {
+	f := family()
+	args := []string{compilerName()}
+	args = append(args, strings.Fields(familyFlags())...)
+	args = append(args, f.includeFlag+"inc")
+	args = append(args, strings.Fields(t.entry.CompilerFlags)...)
+	args = append(args, strings.Fields(moduleFlags(familyName(), t))...)
+	if buildFilesRaiseLimits() {
+		args = append(args, strings.Fields(raisingFlags(familyName(), t.entry.TestName, t.count))...)
+	}
+	args = append(args, strings.Fields(phaseFlag(familyName(), phase))...)
+	args = append(args, strings.Fields(outputFlagOf(familyName(), phase)+output)...)
+	return append(args, source)
+}
+
+// the extension of the object files written by a compiler of the family, or by any tool if the name is empty
+func objectExtension(familyName string) string {
+	if len(familyName) == 0 {
+		return ".o"
+	}
+	return familyOf(familyName).objectExtension
+}
+
+// the flag of a compiler of the family naming the file it writes when it stops after the phase, a trailing space
+// means it is a separate argument
+func outputFlagOf(familyName, phase string) string {
+	f := familyOf(familyName)
+	if phase == compilePhase || phase == interfacePhase {
+		return f.objectOutputFlag
+	}
+	return f.outputFlag
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// a diagnostic message printed by a compiler (or linker), normalized from the various formats
+type Diagnostic struct {
+	File     string `json:"file,omitempty" xml:"file,attr,omitempty"`
+	Line     int    `json:"line,omitempty" xml:"line,attr,omitempty"`
+	Column   int    `json:"column,omitempty" xml:"column,attr,omitempty"`
+	Severity string `json:"severity" xml:"severity,attr"` // one of error, fatal, ice, warning and note
+	Code     string `json:"code,omitempty" xml:"code,attr,omitempty"`
+	Message  string `json:"message" xml:",chardata"`
+}
+
+// the categories of the failures
+const (
+	limitFailure     = "implementation limit"
+	iceFailure       = "ICE"
+	generatorFailure = "syntax error in generator"
+	linkerFailure    = "linker error"
+	outputFailure    = "unexpected output"
+	timeoutFailure   = "timeout"
+	symbolFailure    = "unexpected symbols"
+	debugInfoFailure = "truncated debug information"
+	otherFailure     = "other"
+)
+
+// the maximum number of diagnostics kept for a result, the rest can be found in the saved stderr
+const maxDiagnostics = 100
+
+var (
+	// file:line:column: severity: message, as printed by gcc, clang and icc on linux
+	gccDiagnostic = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)? (fatal error|error|warning|note|internal compiler error|sorry, unimplemented): (.*)$`)
+	// program: severity: message, as printed by the compiler drivers, ie. "g++: internal compiler error: ..."
+	driverDiagnostic = regexp.MustCompile(`^([\w.+-]+): (fatal error|error|warning|internal compiler error): (.*)$`)
+	// file(line[,column]): severity [code]: message, as printed by msvc and icc on windows
+	msvcDiagnostic = regexp.MustCompile(`^(.+?)\((\d+)(?:,(\d+))?\)\s*: (fatal error|error|warning|note|internal error|catastrophic error)(?: (#?[A-Z]*\d+))?: (.*)$`)
+	// file : severity LNKnnnn: message, as printed by the msvc linker
+	msvcLinkerDiagnostic = regexp.MustCompile(`^(.+?) : (fatal error|error|warning) (LNK\d+): (.*)$`)
+	// file:(.section+offset): message, as printed by ld
+	ldDiagnostic = regexp.MustCompile(`^(.+?):\(\.[^)]*\): (.*)$`)
+	// the warning option at the end of a gcc/clang message, used as the code of the diagnostic
+	warningOption = regexp.MustCompile(`\s*\[(-W[\w+=-]+)\]$`)
+)
+
+// the severities of the various compilers, normalized
+var severities = map[string]string{
+	"error":                   "error",
+	"fatal error":             "fatal",
+	"catastrophic error":      "fatal",
+	"sorry, unimplemented":    "error",
+	"internal compiler error": "ice",
+	"internal error":          "ice",
+	"warning":                 "warning",
+	"note":                    "note",
+}
+
+// parses the diagnostics from the stderr of a compiler, notes are dropped since they can be counted in thousands
+func parseDiagnostics(stderr string) []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	scanner := bufio.NewScanner(strings.NewReader(stderr))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() && len(diagnostics) < maxDiagnostics {
+		d, ok := parseDiagnostic(strings.TrimRight(scanner.Text(), "\r"))
+		if ok && d.Severity != "note" {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// parses a line of a compiler output, returns false if it is not a diagnostic
+func parseDiagnostic(line string) (Diagnostic, bool) {
+	if m := msvcLinkerDiagnostic.FindStringSubmatch(line); m != nil {
+		return Diagnostic{File: m[1], Severity: severities[m[2]], Code: m[3], Message: m[4]}, true
+	}
+	if m := msvcDiagnostic.FindStringSubmatch(line); m != nil {
+		lineNumber, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		return Diagnostic{File: m[1], Line: lineNumber, Column: column, Severity: severities[m[4]], Code: m[5], Message: m[6]}, true
+	}
+	if m := gccDiagnostic.FindStringSubmatch(line); m != nil {
+		lineNumber, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		d := Diagnostic{File: m[1], Line: lineNumber, Column: column, Severity: severities[m[4]], Message: m[5]}
+		if w := warningOption.FindStringSubmatch(d.Message); w != nil {
+			d.Code = w[1]
+			d.Message = strings.TrimSuffix(d.Message, w[0])
+		}
+		return d, true
+	}
+	if m := ldDiagnostic.FindStringSubmatch(line); m != nil {
+		return Diagnostic{File: m[1], Severity: "error", Code: "ld", Message: m[2]}, true
+	}
+	if m := driverDiagnostic.FindStringSubmatch(line); m != nil {
+		return Diagnostic{File: m[1], Severity: severities[m[2]], Message: m[3]}, true
+	}
+	return Diagnostic{}, false
+}
+
+var (
+	// messages telling that a limit of the compiler was hit. Only the wording of the limits, a bare "too many" is an
+	// error of the generated code as well (ie. too many arguments to function)
+	limitMessages = regexp.MustCompile(`(?i)compiler limit|nested too deeply|exceeded? (the )?maximum|maximum .*depth|depth exceeds|exceeds maximum|template instantiation depth exceeds|bracket nesting level exceeded|maximum step limit|(array|object|variable|type) .*is too large|out of (heap|memory)|memory exhausted|overflowed|-fbracket-depth|-ftemplate-depth|-fconstexpr-depth|-fconstexpr-steps|-fconstexpr-ops-limit|-fconstexpr-loop-limit`)
+	// messages telling that the compiler crashed
+	iceMessages = regexp.MustCompile(`(?i)internal compiler error|internal error|PLEASE submit a bug report|Segmentation (fault|violation)|core dumped|Stack dump:|frontend command failed due to signal|unexpected problem`)
+	// messages telling that the linking failed
+	linkerMessages = regexp.MustCompile(`(?i)undefined reference|ld returned|linker command failed|relocation truncated|multiple definition|LNK\d+`)
+	// msvc codes of the limits: blocks nested too deeply, token overflow, macros nested too deeply, out of heap, ...
+	msvcLimitCodes = regexp.MustCompile(`^C(1001|1002|1009|1026|1053|1054|1055|1056|1060|1061|1063|1064|1076|1091|1111|1128|1202|1204|1509|2026|2041|2110|2117|2136|2946|3209|7339)$`)
+)
+
+// classifies the failure of a tool from its diagnostics, its stderr and the signal it was terminated with
+func classifyFailure(diagnostics []Diagnostic, stderr string, signal string) string {
+	if len(signal) > 0 || iceMessages.MatchString(stderr) {
+		return iceFailure
+	}
+	for _, d := range diagnostics {
+		if d.Severity == "ice" {
+			return iceFailure
+		}
+	}
+	for _, d := range diagnostics {
+		if d.Severity != "error" && d.Severity != "fatal" {
+			continue
+		}
+		if msvcLimitCodes.MatchString(d.Code) || limitMessages.MatchString(d.Message) {
+			return limitFailure
+		}
+	}
+	if limitMessages.MatchString(stderr) {
+		return limitFailure
+	}
+	if linkerMessages.MatchString(stderr) {
+		return linkerFailure
+	}
+	for _, d := range diagnostics {
+		if d.Severity == "error" || d.Severity == "fatal" {
+			return generatorFailure
+		}
+	}
+	return otherFailure
+}
+
+// the first error (or worse) of the diagnostics, formatted for the results and the report
+func firstError(diagnostics []Diagnostic) string {
+	for _, d := range diagnostics {
+		if d.Severity == "error" || d.Severity == "fatal" || d.Severity == "ice" {
+			if len(d.Code) > 0 {
+				return d.Code + ": " + d.Message
+			}
+			return d.Message
+		}
+	}
+	return ""
+}
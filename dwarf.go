@@ -0,0 +1,172 @@
+package main
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the debug information of the object file or the executable of a result
+type DebugStats struct {
+	DIEs           int            `json:"dies" xml:"dies,attr"`
+	DebugSize      uint64         `json:"debugSize" xml:"debugSize,attr"` // the sum of the sizes of the debug sections
+	Sections       []DebugSection `json:"sections" xml:"section"`
+	MaxDepth       int            `json:"maxDepth" xml:"maxDepth,attr"`             // of the tree of the DIEs
+	NamespaceDepth int            `json:"namespaceDepth" xml:"namespaceDepth,attr"` // of the nested namespaces
+	ClassDepth     int            `json:"classDepth" xml:"classDepth,attr"`         // of the nested classes
+	BaseDepth      int            `json:"baseDepth" xml:"baseDepth,attr"`           // of the generated base classes of Derived
+	DepthCheck     string         `json:"depthCheck,omitempty" xml:"depthCheck,omitempty"`
+}
+
+// the size of a debug section
+type DebugSection struct {
+	Name string `json:"name" xml:"name,attr"`
+	Size uint64 `json:"size" xml:"size,attr"`
+}
+
+// the map which maps the name of a test case to a function checking the debug information for a given count,
+// returns why the nesting found in it is not the generated one or an empty string
+var expectedDebugDepths = map[string]func(n int, stats *DebugStats) string{
+	"scopeQualificationOfOneIdentifier": func(n int, stats *DebugStats) string {
+		return expectDepth(n, "namespaces", stats.NamespaceDepth)
+	},
+	"nestingOfClasses": func(n int, stats *DebugStats) string {
+		return expectDepth(n, "classes", stats.ClassDepth)
+	},
+	"directAndIndirectBaseClassesOfClass": func(n int, stats *DebugStats) string {
+		return expectDepth(classHierarchyDepth(n), "base classes", stats.BaseDepth)
+	},
+	"directAndIndirectVirtualBaseClassesOfClass": func(n int, stats *DebugStats) string {
+		return expectDepth(classHierarchyDepth(n), "base classes", stats.BaseDepth)
+	},
+}
+
+// an empty string if the depth is the generated one, otherwise the difference
+func expectDepth(expected int, what string, actual int) string {
+	if actual >= expected {
+		return ""
+	}
+	return "the debug information nests " + strconv.Itoa(actual) + " " + what + ", " + strconv.Itoa(expected) + " were generated"
+}
+
+// the classes generated by generateClassHierarchyWitClasses, the other classes (ie. of the standard library) are not
+// followed when the depth of the hierarchy is measured
+var generatedClass = regexp.MustCompile(`^(Derived|Base[LR0-9]*)$`)
+
+// the longest chain of base classes of the class, following only the generated ones. The depths of the classes
+// already measured are kept in depths
+func baseDepth(class string, bases map[string][]string, depths map[string]int) int {
+	if depth, ok := depths[class]; ok {
+		return depth
+	}
+	depths[class] = 0
+	depth := 0
+	for _, base := range bases[class] {
+		if !generatedClass.MatchString(base) {
+			continue
+		}
+		if d := baseDepth(base, bases, depths) + 1; d > depth {
+			depth = d
+		}
+	}
+	depths[class] = depth
+	return depth
+}
+
+// analyzes the DWARF debug information of the ELF object file or executable, nil if there is none. The nesting is
+// checked against the expectations of the test for the count
+func analyzeDebugInfo(fileName, testName, count string) *DebugStats {
+	f, err := elf.Open(fileName)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	stats := &DebugStats{}
+	for _, section := range f.Sections {
+		if strings.HasPrefix(section.Name, ".debug_") || strings.HasPrefix(section.Name, ".zdebug_") {
+			stats.Sections = append(stats.Sections, DebugSection{section.Name, section.Size})
+			stats.DebugSize += section.Size
+		}
+	}
+	sort.Slice(stats.Sections, func(i, j int) bool { return stats.Sections[i].Name < stats.Sections[j].Name })
+
+	data, err := f.DWARF()
+	if err != nil {
+		return stats
+	}
+
+	// the DIEs enclosing the current one, the names of the classes and the base classes of the generated ones
+	path := make([]*dwarf.Entry, 0)
+	classNames := make(map[dwarf.Offset]string)
+	baseOffsets := make(map[string][]dwarf.Offset)
+	r := data.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil || entry == nil {
+			break
+		}
+		if entry.Tag == 0 {
+			// the end of the children of the last DIE of the path
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			continue
+		}
+
+		stats.DIEs++
+		switch entry.Tag {
+		case dwarf.TagClassType, dwarf.TagStructType:
+			if name, ok := entry.Val(dwarf.AttrName).(string); ok {
+				classNames[entry.Offset] = name
+			}
+		case dwarf.TagInheritance:
+			base, ok := entry.Val(dwarf.AttrType).(dwarf.Offset)
+			if len(path) > 0 && ok {
+				class := classNames[path[len(path)-1].Offset]
+				if generatedClass.MatchString(class) {
+					baseOffsets[class] = append(baseOffsets[class], base)
+				}
+			}
+		}
+		if entry.Children {
+			path = append(path, entry)
+			if len(path) > stats.MaxDepth {
+				stats.MaxDepth = len(path)
+			}
+			namespaces, classes := 0, 0
+			for _, e := range path {
+				switch e.Tag {
+				case dwarf.TagNamespace:
+					namespaces++
+				case dwarf.TagClassType, dwarf.TagStructType, dwarf.TagUnionType:
+					classes++
+				}
+			}
+			if namespaces > stats.NamespaceDepth {
+				stats.NamespaceDepth = namespaces
+			}
+			if classes > stats.ClassDepth {
+				stats.ClassDepth = classes
+			}
+		}
+	}
+
+	bases := make(map[string][]string)
+	for class, offsets := range baseOffsets {
+		for _, offset := range offsets {
+			bases[class] = append(bases[class], classNames[offset])
+		}
+	}
+	stats.BaseDepth = baseDepth("Derived", bases, make(map[string]int))
+
+	if expected, ok := expectedDebugDepths[testName]; ok {
+		if n, err := strconv.Atoi(count); err == nil {
+			stats.DepthCheck = expected(n, stats)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strconv"
+)
+
+// the map which maps the name of a test case to a function calculating a regular expression matching the output
+// of the compiled test for a given count. An empty expression means the output cannot be predicted (random values)
+var expectedOutputs = map[string]func(int) string{
+	"nestingOfStatements":                                      func(n int) string { return exactly(n / 2 * 2) },
+	"nestingLevelOfConditionalInclusion":                       exactly,
+	"pointerAndArrayDeclaratorsModifyingSomething":             exactly,
+	"nestingLevelsOfParenthesizedExpressionsInAFullExpression": func(n int) string { return exactly(n + 1) },
+	"identifierOrMacroNameLength":                              exactly,
+	"externIdentifierNameLength":                               exactly,
+	"externIdentifiersInOneTranslationUnit":                    exactly,
+	"identifiersWithBlockScopeDeclaredInOneBlock":              exactly,
+	"parameterCountInFunctionDefinition":                       exactlyUnlessRandom,
+	"structuredBindingsInOneDeclaration":                       exactly,
+	"macroCountInOneTranslationUnit":                           exactly,
+	"parametersInMacroDefinition":                              exactly,
+	"charactersInOneLogicalSourceLine":                         exactly,
+	"charactersInAStringLiteral":                               exactly,
+	"sizeOfAnObject":                                           exactly,
+	"nestingLevelsForIncludes":                                 exactly,
+	"caseLabelsForSwitch":                                      unpredictable,
+	"nonStaticDataMembersOfClass":                              exactlyUnlessRandom,
+	"lambdaCapturesInOneLambdaExpression":                      exactly,
+	"enumerationConstantsInEnum":                               unpredictable,
+	"nestingOfClasses":                                         exactly,
+	"functionsRegisteredByatexit":                              startsWith,
+	"functionsRegisteredByat_quick_exit":                       startsWith,
+	"directAndIndirectBaseClassesOfClass":                      func(n int) string { return endsWith(classHierarchySize(n)) },
+	"directBaseClassesOfClass":                                 func(n int) string { return endsWith(n * (n - 1) / 2) },
+	"classMembersDeclaredInASingleMemberSpecification":         exactly,
+	"finalOverridingVirtualFunctions":                          func(n int) string { return exactly(classHierarchySize(n)) },
+	"directAndIndirectVirtualBaseClassesOfClass":               func(n int) string { return endsWith(classHierarchySize(n)) },
+	"staticDataMemberOfClass":                                  exactlyUnlessRandom,
+	"friendsOfAClass":                                          exactly,
+	"accessControlDeclarationsInClass":                         func(n int) string { return exactly(n - 1) },
+	"memberInitializersInAConstructorDefinition":               exactly,
+	"initializerClauseInBracedInitList":                        exactly,
+	"scopeQualificationOfOneIdentifier":                        exactly,
+	"nestedLinkageSpecifiers":                                  exactly,
+	"recursiveConstexpr":                                       sumUpTo,
+	"fullExpressionInAConst":                                   func(n int) string { return exactly(n - 1) },
+	"constexprEvaluationSteps":                                 exactly,
+	"constexprLoopIterations":                                  exactly,
+	"constexprAllocationSize":                                  exactly,
+	"constevalCallDepth":                                       sumUpTo,
+	"templateParametersInTemplateDeclaration":                  exactly,
+	"recursivelyNestedTemplateInstantiations":                  exactly,
+	"elementsInPackExpansion":                                  exactly,
+	"operandsInFoldExpression":                                 exactly,
+	"nestedPackExpansions":                                     func(n int) string { return exactly(n * n) },
+	"typesInSizeofPack":                                        exactly,
+	"elementsInTuple":                                          exactly,
+	"alternativesInVariant":                                    exactly,
+	"handlersPerTryBlock":                                      exactly,
+	"numberOfPlaceholders":                                     exactly,
+	"externIdentifiersInTranslationUnits":                      exactly,
+	"inlineFunctionsInTranslationUnits":                        exactly,
+	"objectFilesInStaticLibrary":                               exactly,
+	"virtualFunctionsInTranslationUnits":                       exactly,
+	"declaratorsModifyingAType":                                exactly,
+	"membersInAStructure":                                      exactly,
+	"nestingOfStructureDefinitions":                            exactly,
+	"conjunctionsInOneRequiresClause":                          exactly,
+	"nestingOfRequiresExpressions":                             exactly,
+	"conceptsSubsumedInOverloadResolution":                     exactly,
+	"constrainedOverloadsInOneOverloadSet":                     exactly,
+	"moduleInterfacesImportedByOneTranslationUnit":             exactly,
+	"partitionsInOneModule":                                    exactly,
+	"exportedDeclarationsInOneModule":                          exactly,
+	"evalMacroRecursionDepth":                                  exactly,
+	"argumentsInVariadicMacro":                                 exactly,
+	"nestingOfVaOptExpansions":                                 exactly,
+	"pasteOperatorsInOneReplacement":                           exactly,
+	"distinctHeadersIncluded":                                  exactly,
+}
+
+// returns the regular expression matching the output of the given test for the given count, or an empty string
+func expectedOutput(testName, count string) string {
+	f, ok := expectedOutputs[testName]
+	if !ok {
+		return ""
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return ""
+	}
+	return f(n)
+}
+
+// the output is the sum of the numbers from 1 to n, which does not fit into an int
+func sumUpTo(n int) string {
+	return "^" + strconv.FormatUint(uint64(n)*uint64(n+1)/2, 10) + "[^0-9]*$"
+}
+
+// the output is a single number
+func exactly(v int) string {
+	return "^" + strconv.Itoa(v) + "[^0-9]*$"
+}
+
+// the output starts with the number, followed by something else (such as the output of the exit handlers)
+func startsWith(v int) string {
+	return "^" + strconv.Itoa(v) + "[^0-9]"
+}
+
+// the output is a list of numbers, the last one being the checked one
+func endsWith(v int) string {
+	return "(^|[^0-9])" + strconv.Itoa(v) + "[^0-9]*$"
+}
+
+// the output is the count, as long as the generated values are all ones
+func exactlyUnlessRandom(v int) string {
+	if testSet.RandomBehaviour {
+		return ""
+	}
+	return exactly(v)
+}
+
+func unpredictable(int) string {
+	return ""
+}
+
+// the number of classes generateClassHierarchyWitClasses and finalOverridingVirtualFunctions will generate for count
+func classHierarchySize(count int) int {
+	_, totalCounter := classHierarchyTree(count)
+	if count > totalCounter {
+		return count
+	}
+	return totalCounter
+}
+
+// the longest chain of base classes of Derived generateClassHierarchyWitClasses will generate for count: Base and
+// the levels of the tree of its base classes, the classes filling the gap being direct base classes of Derived
+func classHierarchyDepth(count int) int {
+	root, _ := classHierarchyTree(count)
+	return treeHeight(root)
+}
+
+// the tree of the base classes of Base generated for count, and the number of its nodes
+func classHierarchyTree(count int) (*treeNode, int) {
+	maxLevel := 0
+	for c := count; c > 1; c /= 2 {
+		maxLevel += 1
+	}
+
+	root := &treeNode{nil, nil, "Base"}
+	totalCounter := 1
+	generateChildrensForNode(root, 1, maxLevel-1, &totalCounter, root.data)
+	return root, totalCounter
+}
+
+// the number of nodes on the longest path from the node to a leaf
+func treeHeight(node *treeNode) int {
+	if node == nil {
+		return 0
+	}
+	left, right := treeHeight(node.left), treeHeight(node.right)
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
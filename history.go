@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// a run as stored in the history
+type runRecord struct {
+	ID        string             `json:"id"`
+	Time      string             `json:"time"`
+	SetName   string             `json:"setName"`
+	Compilers []CompilerIdentity `json:"compilers"`
+	Results   int                `json:"results"`
+	Manifest  *Manifest          `json:"manifest,omitempty"`
+}
+
+// a result as stored in the history, with the run it belongs to
+type historyRecord struct {
+	Run     string     `json:"run"`
+	Time    string     `json:"time"`
+	SetName string     `json:"setName"`
+	Result  TestResult `json:"result"`
+}
+
+// the directory of the history, as given in the json file or "history" in the working directory
+func historyDir() string {
+	if len(testSet.HistoryDir) > 0 {
+		return testSet.HistoryDir
+	}
+	return "history"
+}
+
+// the key the results of a tool are stored under in the history: the identity of its compiler, so the results of
+// the same compiler are found even if the tool is named differently, or the name of the tool if it is not a compiler
+func historyKey(tool string, identity *CompilerIdentity) string {
+	if identity == nil {
+		return fileNameSafe("tool-" + tool)
+	}
+	key := identity.Family + "-" + identity.Version
+	if len(identity.Target) > 0 {
+		key += "-" + identity.Target
+	}
+	return fileNameSafe(key)
+}
+
+// the file the results stored under the key are kept in
+func historyFile(key string) string {
+	return filepath.Join(historyDir(), "results", key+".jsonl")
+}
+
+// the file the runs are kept in
+func runsFile() string {
+	return filepath.Join(historyDir(), "runs.jsonl")
+}
+
+// reads a file of json lines, calling add with each of them. A missing file is an empty one
+func readJSONLines(fileName string, add func(line []byte)) {
+	f, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return
+	}
+	check(err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 {
+			add([]byte(line))
+		}
+	}
+	check(scanner.Err())
+}
+
+// appends the values to a file of json lines
+func appendJSONLines(fileName string, values []interface{}) {
+	check(os.MkdirAll(filepath.Dir(fileName), os.ModePerm))
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	check(err)
+	defer f.Close()
+
+	for _, v := range values {
+		line, err := json.Marshal(v)
+		check(err)
+		_, err = f.Write(append(line, '\n'))
+		check(err)
+	}
+}
+
+// loads the results stored under the key, oldest first
+func loadHistory(key string) []historyRecord {
+	records := make([]historyRecord, 0)
+	readJSONLines(historyFile(key), func(line []byte) {
+		var record historyRecord
+		check(json.Unmarshal(line, &record))
+		records = append(records, record)
+	})
+	return records
+}
+
+// loads the results stored under all the keys, oldest first
+func loadAllHistory() []historyRecord {
+	files, err := filepath.Glob(filepath.Join(historyDir(), "results", "*.jsonl"))
+	check(err)
+
+	records := make([]historyRecord, 0)
+	for _, fileName := range files {
+		records = append(records, loadHistory(strings.TrimSuffix(filepath.Base(fileName), ".jsonl"))...)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Run < records[j].Run })
+	return records
+}
+
+// loads the runs, oldest first
+func loadRuns() []runRecord {
+	runs := make([]runRecord, 0)
+	readJSONLines(runsFile(), func(line []byte) {
+		var run runRecord
+		check(json.Unmarshal(line, &run))
+		runs = append(runs, run)
+	})
+	return runs
+}
+
+// appends a run with its manifest and its results to the history, each result under the key of its tool. The tests which were not
+// run are not stored
+func appendHistory(run RunResults, manifest *Manifest) {
+	now := time.Now()
+	record := runRecord{ID: now.UTC().Format("20060102T150405.000000Z"), Time: now.Format(time.RFC3339),
+		SetName: run.SetName, Compilers: run.Compilers, Manifest: manifest}
+
+	results := make(map[string][]interface{})
+	for _, r := range run.Results {
+		if len(r.Skipped) > 0 {
+			continue
+		}
+		key := historyKey(r.Tool, r.Compiler)
+		results[key] = append(results[key], historyRecord{Run: record.ID, Time: record.Time, SetName: run.SetName, Result: r})
+		record.Results++
+	}
+
+	for key, records := range results {
+		appendJSONLines(historyFile(key), records)
+	}
+	appendJSONLines(runsFile(), []interface{}{record})
+}
+
+// the limit of a test found for one compiler in one mode, phase and standard: the largest count which passed and the smallest
+// which failed
+type limitRow struct {
+	Test           string `json:"test"`
+	Compiler       string `json:"compiler"`
+	Family         string `json:"family"`
+	Version        string `json:"version"`
+	Mode           string `json:"mode"`
+	Phase          string `json:"phase"`
+	Std            string `json:"std,omitempty"`
+	LargestPassed  string `json:"largestPassed"`
+	SmallestFailed string `json:"smallestFailed"`
+	Failure        string `json:"failure"`
+	Runs           int    `json:"runs"`
+	LastRun        string `json:"lastRun"`
+}
+
+// one measurement of a test, for following its trend
+type trendRow struct {
+	Test     string  `json:"test"`
+	Time     string  `json:"time"`
+	Compiler string  `json:"compiler"`
+	Family   string  `json:"family"`
+	Version  string  `json:"version"`
+	Mode     string  `json:"mode"`
+	Phase    string  `json:"phase"`
+	Std      string  `json:"std,omitempty"`
+	Count    string  `json:"count"`
+	Passed   bool    `json:"passed"`
+	WallTime float64 `json:"wallTime"`
+	MaxRSS   int64   `json:"maxRSS"`
+	Failure  string  `json:"failure"`
+}
+
+// the filters of the history queries, an empty one matches everything
+type historyFilter struct {
+	test, count, family, version, tool, mode, phase, std string
+}
+
+func (filter historyFilter) matches(record historyRecord) bool {
+	r := record.Result
+	family, version := "", ""
+	if r.Compiler != nil {
+		family, version = r.Compiler.Family, r.Compiler.Version
+	}
+	return (len(filter.test) == 0 || r.Test == filter.test) &&
+		(len(filter.count) == 0 || r.Count == filter.count) &&
+		(len(filter.family) == 0 || family == filter.family) &&
+		(len(filter.version) == 0 || strings.HasPrefix(version, filter.version)) &&
+		(len(filter.tool) == 0 || r.Tool == filter.tool) &&
+		(len(filter.mode) == 0 || r.Mode == filter.mode) &&
+		(len(filter.phase) == 0 || r.Phase == filter.phase) &&
+		(len(filter.std) == 0 || r.Std == filter.std)
+}
+
+// the name of the compiler of a result in the queries: its identity, or the tool if it is not a compiler
+func compilerOf(r TestResult) (string, string, string) {
+	if r.Compiler == nil {
+		return r.Tool, "", ""
+	}
+	return r.Compiler.Family + " " + r.Compiler.Version + " " + r.Compiler.Target, r.Compiler.Family, r.Compiler.Version
+}
+
+// the limits of the tests for each compiler, mode, phase and standard
+func queryLimits(records []historyRecord) []limitRow {
+	type key struct{ compiler, test, mode, phase, std string }
+	rows := make(map[key]*limitRow)
+	runs := make(map[key]map[string]bool)
+	keys := make([]key, 0)
+
+	for _, record := range records {
+		r := record.Result
+		compiler, family, version := compilerOf(r)
+		k := key{compiler, r.Test, r.Mode, r.Phase + debugName(r.Debug), r.Std}
+		row, ok := rows[k]
+		if !ok {
+			row = &limitRow{Test: r.Test, Compiler: compiler, Family: family, Version: version, Mode: r.Mode,
+				Phase: k.phase, Std: r.Std}
+			rows[k] = row
+			runs[k] = make(map[string]bool)
+			keys = append(keys, k)
+		}
+		runs[k][record.Run] = true
+		row.Runs = len(runs[k])
+		row.LastRun = record.Time
+
+		if r.Passed {
+			if countGreater(r.Count, row.LargestPassed) {
+				row.LargestPassed = r.Count
+			}
+		} else if len(row.SmallestFailed) == 0 || countGreater(row.SmallestFailed, r.Count) {
+			row.SmallestFailed, row.Failure = r.Count, r.Category
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].test != keys[j].test {
+			return keys[i].test < keys[j].test
+		}
+		if keys[i].compiler != keys[j].compiler {
+			return keys[i].compiler < keys[j].compiler
+		}
+		if keys[i].mode != keys[j].mode {
+			return keys[i].mode < keys[j].mode
+		}
+		if keys[i].phase != keys[j].phase {
+			// the phases with debug information come right after the ones without it
+			return phaseIndex(strings.TrimSuffix(keys[i].phase, debugName(true))) <
+				phaseIndex(strings.TrimSuffix(keys[j].phase, debugName(true)))
+		}
+		return standardIndex(keys[i].std) < standardIndex(keys[j].std)
+	})
+	result := make([]limitRow, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, *rows[k])
+	}
+	return result
+}
+
+// the measurements of the tests, oldest first
+func queryTrend(records []historyRecord) []trendRow {
+	rows := make([]trendRow, 0, len(records))
+	for _, record := range records {
+		r := record.Result
+		compiler, family, version := compilerOf(r)
+		rows = append(rows, trendRow{Test: r.Test, Time: record.Time, Compiler: compiler, Family: family, Version: version,
+			Mode: r.Mode, Phase: r.Phase + debugName(r.Debug), Std: r.Std, Count: r.Count, Passed: r.Passed, WallTime: r.WallTime, MaxRSS: r.MaxRSS, Failure: r.Category})
+	}
+	return rows
+}
+
+// compares two counts numerically, an empty count being the smallest one
+func countGreater(a, b string) bool {
+	if len(b) == 0 {
+		return len(a) > 0
+	}
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return x > y
+}
+
+// queries the history of the results: the runs, the limits of the tests, the trends of their measurements or the
+// results themselves
+func historyCommand(args []string) {
+	flags, config := commandFlags("history")
+	query := flags.String("query", "limit", "what to show: runs, limit, trend or results")
+	format := flags.String("format", "table", "the output format: table or json")
+	var filter historyFilter
+	flags.StringVar(&filter.test, "test", "", "only the results of the test")
+	flags.StringVar(&filter.count, "count", "", "only the results with the count")
+	flags.StringVar(&filter.family, "family", "", "only the results of the compiler family")
+	flags.StringVar(&filter.version, "version", "", "only the results of the compiler versions starting with it")
+	flags.StringVar(&filter.tool, "tool", "", "only the results of the tool")
+	flags.StringVar(&filter.mode, "mode", "", "only the results with the limits, default or raised")
+	flags.StringVar(&filter.phase, "phase", "", "only the results of the phase: preprocess, syntax, compile or link")
+	flags.StringVar(&filter.std, "std", "", "only the results of the standard of a sweep, ie. c++17")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*config); err == nil {
+		loadTestSet(*config)
+	}
+
+	records := make([]historyRecord, 0)
+	for _, record := range loadAllHistory() {
+		if filter.matches(record) {
+			records = append(records, record)
+		}
+	}
+
+	var rows interface{}
+	var n int
+	var header []string
+	var cells func(i int) []string
+	switch *query {
+	case "runs":
+		runs := loadRuns()
+		rows, n = runs, len(runs)
+		header = []string{"RUN", "TIME", "SET", "COMPILERS", "RESULTS"}
+		cells = func(i int) []string {
+			compilers := make([]string, 0)
+			for _, c := range runs[i].Compilers {
+				compilers = append(compilers, c.Family+" "+c.Version)
+			}
+			return []string{runs[i].ID, runs[i].Time, runs[i].SetName, strings.Join(compilers, ", "), strconv.Itoa(runs[i].Results)}
+		}
+	case "limit":
+		limits := queryLimits(records)
+		rows, n = limits, len(limits)
+		header = []string{"TEST", "COMPILER", "MODE", "PHASE", "STD", "LARGEST PASSED", "SMALLEST FAILED", "FAILURE", "RUNS", "LAST RUN"}
+		cells = func(i int) []string {
+			l := limits[i]
+			return []string{l.Test, l.Compiler, l.Mode, l.Phase, l.Std, l.LargestPassed, l.SmallestFailed, l.Failure, strconv.Itoa(l.Runs), l.LastRun}
+		}
+	case "trend":
+		trend := queryTrend(records)
+		rows, n = trend, len(trend)
+		header = []string{"TIME", "TEST", "COMPILER", "MODE", "PHASE", "STD", "COUNT", "PASSED", "TIME (S)", "MEMORY (KB)", "FAILURE"}
+		cells = func(i int) []string {
+			t := trend[i]
+			return []string{t.Time, t.Test, t.Compiler, t.Mode, t.Phase, t.Std, t.Count, strconv.FormatBool(t.Passed),
+				strconv.FormatFloat(t.WallTime, 'f', 3, 64), strconv.FormatInt(t.MaxRSS, 10), t.Failure}
+		}
+	case "results":
+		rows, n = records, len(records)
+		header = csvHeader
+		cells = func(i int) []string { return records[i].Result.csvRecord() }
+	default:
+		fmt.Println("unknown query:", *query)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "json" {
+		content, err := json.MarshalIndent(rows, "", "  ")
+		check(err)
+		fmt.Println(string(content))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for i := 0; i < n; i++ {
+		fmt.Fprintln(w, strings.Join(cells(i), "\t"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,111 @@
+package main
+
+// the languages the tests can be generated in, the value of "language" in the json file
+const (
+	cppLanguage = "c++"
+	cLanguage   = "c"
+)
+
+// the tests which can also be generated in C, checking the translation limits of the C standard (5.2.4.1). All the
+// tests can be generated in C++
+var cTests = map[string]bool{
+	"nestingLevelOfConditionalInclusion":    true,
+	"externIdentifiersInOneTranslationUnit": true,
+	"macroCountInOneTranslationUnit":        true,
+	"parametersInMacroDefinition":           true,
+	"charactersInAStringLiteral":            true,
+	"caseLabelsForSwitch":                   true,
+	"declaratorsModifyingAType":             true,
+	"membersInAStructure":                   true,
+	"nestingOfStructureDefinitions":         true,
+	"evalMacroRecursionDepth":               true,
+	"argumentsInVariadicMacro":              true,
+	"nestingOfVaOptExpansions":              true,
+	"pasteOperatorsInOneReplacement":        true,
+}
+
+// the language of the tests of the test set, C++ by default
+func language() string {
+	switch testSet.Language {
+	case "", cppLanguage:
+		return cppLanguage
+	case cLanguage:
+		return cLanguage
+	}
+	panic("unknown language: " + testSet.Language)
+}
+
+// whether the test can be generated in the language of the test set
+func hasLanguageVersion(testName string) bool {
+	return language() == cppLanguage || cTests[testName]
+}
+
+// the extension of the generated source files
+func sourceExtension() string {
+	if language() == cLanguage {
+		return ".c"
+	}
+	return ".cpp"
+}
+
+// the extension of the preprocessed source files
+func preprocessedExtension() string {
+	if language() == cLanguage {
+		return ".i"
+	}
+	return ".ii"
+}
+
+// the include of the header needed for printing the result of a test
+func outputHeader() string {
+	if language() == cLanguage {
+		return "#include <stdio.h>\n\n"
+	}
+	return iostream
+}
+
+// the statement printing the value of an int expression on a line of its own
+func printLine(expression string) string {
+	if language() == cLanguage {
+		return "printf(\"%d\\n\", " + expression + ");"
+	}
+	return "std::cout << " + expression + " << std::endl;"
+}
+
+// the variables of the Makefile holding the compiler and its flags
+func makeVariables() (string, string) {
+	if language() == cLanguage {
+		return "CC", "CFLAGS"
+	}
+	return "CXX", "CXXFLAGS"
+}
+
+// the command of the Makefile compiling with the compiler of the test set and the flags of the test, the flags raising
+// its limits only if the switch of the raised limits is set
+func makeCompileCommand(t generatedTest) string {
+	compiler, flags := makeVariables()
+	command := "$(" + compiler + ") $(" + flags + ") " + testFlags(*t.entry)
+	if raising := raisingFlags(familyName(), t.entry.TestName, t.count); len(raising) > 0 {
+		command += "$(if $(" + raisedLimitsSwitch + ")," + raising + ") "
+	}
+	if modules := moduleFlags(familyName(), t); len(modules) > 0 {
+		command += modules + " "
+	}
+	return command
+}
+
+// the name of the language in a CMake project
+func cmakeLanguage() string {
+	if language() == cLanguage {
+		return "C"
+	}
+	return "CXX"
+}
+
+// the label of the tests in CTest, after the part of the standard listing the limits
+func standardLabel() string {
+	if language() == cLanguage {
+		return "translationLimits"
+	}
+	return "annexB"
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// the modes the tests can be run in: with the default limits of the compilers, or with the limits raised by flags
+const (
+	defaultLimits = "default"
+	raisedLimits  = "raised"
+)
+
+// calculates the flags raising the limit stressed by a test so that the given count fits into it with a compiler of
+// the family
+type limitFlags func(count int, familyName string) string
+
+// the flags raising the limits of the compilers, per family and test. The limits are raised a bit above the count,
+// since the generated code usually needs a few levels more than the count (ie. the braces of main), but never below
+// the defaults of the compiler, so the raised limits are never stricter than the default ones
+var limitRaisingFlags = map[string]map[string]limitFlags{
+	"gcc": {
+		"recursiveConstexpr":                      flagWithLimit("-fconstexpr-depth=", 16),
+		"fullExpressionInAConst":                  flagWithLimit("-fconstexpr-ops-limit=", 1<<25),
+		"recursivelyNestedTemplateInstantiations": flagWithLimit("-ftemplate-depth=", 16),
+		"elementsInTuple":                         flagWithLimit("-ftemplate-depth=", 16),
+		"constexprEvaluationSteps":                flagWithFactor("-fconstexpr-ops-limit=", 64),
+		"constexprLoopIterations":                 allFlags(flagWithLimit("-fconstexpr-loop-limit=", 16), flagWithFactor("-fconstexpr-ops-limit=", 64)),
+		"constexprAllocationSize":                 allFlags(flagWithLimit("-fconstexpr-loop-limit=", 16), flagWithFactor("-fconstexpr-ops-limit=", 64)),
+		"constevalCallDepth":                      flagWithLimit("-fconstexpr-depth=", 16),
+	},
+	"clang": {
+		"pointerAndArrayDeclaratorsModifyingSomething":             flagWithLimit("-fbracket-depth=", 16),
+		"nestingLevelsOfParenthesizedExpressionsInAFullExpression": flagWithLimit("-fbracket-depth=", 16),
+		"nestingOfClasses":                        flagWithLimit("-fbracket-depth=", 16),
+		"scopeQualificationOfOneIdentifier":       flagWithLimit("-fbracket-depth=", 16),
+		"nestedLinkageSpecifiers":                 flagWithLimit("-fbracket-depth=", 16),
+		"recursiveConstexpr":                      flagWithLimit("-fconstexpr-depth=", 16),
+		"fullExpressionInAConst":                  flagWithLimit("-fconstexpr-steps=", 1<<20),
+		"recursivelyNestedTemplateInstantiations": flagWithLimit("-ftemplate-depth=", 16),
+		"elementsInTuple":                         flagWithLimit("-ftemplate-depth=", 16),
+		"nestingOfRequiresExpressions":            flagWithLimit("-fbracket-depth=", 16),
+		"operandsInFoldExpression":                flagWithLimit("-fbracket-depth=", 16),
+		"nestedPackExpansions":                    flagWithLimit("-fbracket-depth=", 16),
+		"constexprEvaluationSteps":                flagWithFactor("-fconstexpr-steps=", 16),
+		"constexprLoopIterations":                 flagWithFactor("-fconstexpr-steps=", 16),
+		"constexprAllocationSize":                 flagWithFactor("-fconstexpr-steps=", 16),
+		"constevalCallDepth":                      flagWithLimit("-fconstexpr-depth=", 16),
+	},
+	"icc": {
+		"recursivelyNestedTemplateInstantiations": flagWithLimit("-ftemplate-depth=", 16),
+		"elementsInTuple":                         flagWithLimit("-ftemplate-depth=", 16),
+	},
+	"msvc": {
+		"recursiveConstexpr":                    flagWithLimit("/constexpr:depth", 16),
+		"fullExpressionInAConst":                flagWithLimit("/constexpr:steps", 1<<20),
+		"constexprEvaluationSteps":              flagWithFactor("/constexpr:steps", 16),
+		"constexprLoopIterations":               flagWithFactor("/constexpr:steps", 16),
+		"constexprAllocationSize":               flagWithFactor("/constexpr:steps", 16),
+		"constevalCallDepth":                    flagWithLimit("/constexpr:depth", 16),
+		"externIdentifiersInOneTranslationUnit": constantFlag("/bigobj"),
+		"sizeOfAnObject":                        constantFlag("/bigobj"),
+	},
+}
+
+// the default values of the limits of the compilers, per family and flag
+var compilerLimitDefaults = map[string]map[string]int{
+	"gcc": {
+		"-fconstexpr-depth=":      512,
+		"-fconstexpr-ops-limit=":  1 << 25,
+		"-fconstexpr-loop-limit=": 1 << 18,
+		"-ftemplate-depth=":       900,
+	},
+	"clang": {
+		"-fbracket-depth=":   256,
+		"-fconstexpr-depth=": 512,
+		"-fconstexpr-steps=": 1 << 20,
+		"-ftemplate-depth=":  1024,
+	},
+	"icc": {
+		"-ftemplate-depth=": 1024,
+	},
+	"msvc": {
+		"/constexpr:depth": 512,
+		"/constexpr:steps": 100000,
+	},
+}
+
+// a flag whose value is the count plus a margin, at least the default of the compiler
+func flagWithLimit(flag string, margin int) limitFlags {
+	return func(count int, familyName string) string {
+		return flag + strconv.Itoa(atLeast(count+margin, compilerLimitDefaults[familyName][flag]))
+	}
+}
+
+// a flag whose value is a multiple of the count, for the limits counting the steps of the work done per element, at
+// least the default of the compiler
+func flagWithFactor(flag string, factor int) limitFlags {
+	return func(count int, familyName string) string {
+		return flag + strconv.Itoa(atLeast(count*factor, compilerLimitDefaults[familyName][flag]))
+	}
+}
+
+// the value, or the minimum if it is smaller
+func atLeast(value, minimum int) int {
+	if value < minimum {
+		return minimum
+	}
+	return value
+}
+
+// all of the flags, for the tests running into several limits
+func allFlags(flags ...limitFlags) limitFlags {
+	return func(count int, familyName string) string {
+		values := make([]string, 0, len(flags))
+		for _, f := range flags {
+			values = append(values, f(count, familyName))
+		}
+		return strings.Join(values, " ")
+	}
+}
+
+// a flag not depending on the count
+func constantFlag(flag string) limitFlags {
+	return func(int, string) string {
+		return flag
+	}
+}
+
+// the flags raising the limits of a compiler of the family for the test, empty if there is nothing to raise
+func raisingFlags(familyName, testName, count string) string {
+	f, ok := limitRaisingFlags[familyName][testName]
+	if !ok {
+		return ""
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return ""
+	}
+	return f(n, familyName)
+}
+
+// the modes the tests are run in, by default only with the default limits
+func limitModes() []string {
+	if len(testSet.LimitModes) > 0 {
+		return testSet.LimitModes
+	}
+	return []string{defaultLimits}
+}
+
+// the switch of the generated build files compiling the tests with the flags raising their limits, a variable of the
+// Makefile (raising them if not empty) and an option of the CMakeLists.txt
+const raisedLimitsSwitch = "STRESS_RAISED_LIMITS"
+
+// whether the generated build files raise the limits unless told otherwise, which they do if the test set is run only
+// with the raised limits. The compile_commands.json follows the same choice
+func buildFilesRaiseLimits() bool {
+	modes := limitModes()
+	return len(modes) == 1 && modes[0] == raisedLimits
+}
@@ -2,17 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// the map which maps the name of a test case from the json file to a go function
+// the map which maps the name of a test case from the json file to a go function, returning the name of the main
+// source file of the test, and its other translation units if it is made of several
 var funcMap = map[string]interface{}{
 	"nestingOfStatements":                                      nestingOfStatements,
 	"nestingLevelOfConditionalInclusion":                       nestingLevelOfConditionalInclusion,
@@ -51,10 +52,39 @@ var funcMap = map[string]interface{}{
 	"nestedLinkageSpecifiers":                                  nestedLinkageSpecifiers,
 	"recursiveConstexpr":                                       recursiveConstexpr,
 	"fullExpressionInAConst":                                   fullExpressionInAConst,
+	"constexprEvaluationSteps":                                 constexprEvaluationSteps,
+	"constexprLoopIterations":                                  constexprLoopIterations,
+	"constexprAllocationSize":                                  constexprAllocationSize,
+	"constevalCallDepth":                                       constevalCallDepth,
 	"templateParametersInTemplateDeclaration":                  templateParametersInTemplateDeclaration,
 	"recursivelyNestedTemplateInstantiations":                  recursivelyNestedTemplateInstantiations,
+	"elementsInPackExpansion":                                  elementsInPackExpansion,
+	"operandsInFoldExpression":                                 operandsInFoldExpression,
+	"nestedPackExpansions":                                     nestedPackExpansions,
+	"typesInSizeofPack":                                        typesInSizeofPack,
+	"elementsInTuple":                                          elementsInTuple,
+	"alternativesInVariant":                                    alternativesInVariant,
 	"handlersPerTryBlock":                                      handlersPerTryBlock,
 	"numberOfPlaceholders":                                     numberOfPlaceholders,
+	"externIdentifiersInTranslationUnits":                      externIdentifiersInTranslationUnits,
+	"inlineFunctionsInTranslationUnits":                        inlineFunctionsInTranslationUnits,
+	"objectFilesInStaticLibrary":                               objectFilesInStaticLibrary,
+	"virtualFunctionsInTranslationUnits":                       virtualFunctionsInTranslationUnits,
+	"declaratorsModifyingAType":                                declaratorsModifyingAType,
+	"membersInAStructure":                                      membersInAStructure,
+	"nestingOfStructureDefinitions":                            nestingOfStructureDefinitions,
+	"conjunctionsInOneRequiresClause":                          conjunctionsInOneRequiresClause,
+	"nestingOfRequiresExpressions":                             nestingOfRequiresExpressions,
+	"conceptsSubsumedInOverloadResolution":                     conceptsSubsumedInOverloadResolution,
+	"constrainedOverloadsInOneOverloadSet":                     constrainedOverloadsInOneOverloadSet,
+	"moduleInterfacesImportedByOneTranslationUnit":             moduleInterfacesImportedByOneTranslationUnit,
+	"partitionsInOneModule":                                    partitionsInOneModule,
+	"exportedDeclarationsInOneModule":                          exportedDeclarationsInOneModule,
+	"evalMacroRecursionDepth":                                  evalMacroRecursionDepth,
+	"argumentsInVariadicMacro":                                 argumentsInVariadicMacro,
+	"nestingOfVaOptExpansions":                                 nestingOfVaOptExpansions,
+	"pasteOperatorsInOneReplacement":                           pasteOperatorsInOneReplacement,
+	"distinctHeadersIncluded":                                  distinctHeadersIncluded,
 }
 
 //
@@ -67,7 +97,7 @@ var funcMap = map[string]interface{}{
 func nestingOfStatements(count string) string {
 	requiredNestingDepth, _ := strconv.Atoi(count)
 
-	content := iostream + "int leave() { exit(0); return 1; }\nint main() {\n"
+	content := iostream + "#include <cstdlib>\n\nint leave() { exit(0); return 1; }\nint main() {\n"
 
 	forCounter := 0
 	modCounter := 2
@@ -124,12 +154,12 @@ func nestingLevelOfConditionalInclusion(count string) string {
 		content += repeat(" ", i) + "#if defined COND_" + strconv.Itoa(i) + "\n"
 	}
 
-	content += "\n" + repeat(" ", requiredCount) + iostream
+	content += "\n" + repeat(" ", requiredCount) + outputHeader()
 	for i := 0; i < requiredCount; i++ {
 		content += repeat(" ", requiredCount-i-1) + "#endif\n"
 	}
 
-	content += "\nint main() {\n\tstd::cout << " + count + " << std::endl;\n}"
+	content += "\nint main() {\n\t" + printLine(count) + "\n}"
 
 	return writeTestFile(trace(), count, content)
 }
@@ -212,9 +242,9 @@ func identifierOrMacroNameLength(count string) string {
 	varName := "v"
 	funName := "f"
 	for i := 1; i < requiredCount; i++ {
-		macroName += string(rune(65 + rand.Intn(26)))
-		varName += string(rune(97 + rand.Intn(26)))
-		funName += string(rune(97 + rand.Intn(26)))
+		macroName += string(rune(65 + random.Intn(26)))
+		varName += string(rune(97 + random.Intn(26)))
+		funName += string(rune(97 + random.Intn(26)))
 	}
 	content += macroName + " " + count + "\n"
 
@@ -232,7 +262,7 @@ func externIdentifierNameLength(count string) string {
 	requiredCount, _ := strconv.Atoi(count)
 	varName := "v"
 	for i := 1; i < requiredCount; i++ {
-		varName += string(rune(97 + rand.Intn(26)))
+		varName += string(rune(97 + random.Intn(26)))
 	}
 
 	content += "int main() {\n\textern int " + varName + ";\n\tstd::cout << " + varName + " << std::endl;\n}\n"
@@ -245,7 +275,7 @@ func externIdentifierNameLength(count string) string {
 // (2.7) External identifiers ([basic.link]) in one translation unit [65 536]
 //
 func externIdentifiersInOneTranslationUnit(count string) string {
-	content := iostream
+	content := outputHeader()
 	requiredCount, _ := strconv.Atoi(count)
 	content += "int main() {"
 	addition := "0"
@@ -255,7 +285,7 @@ func externIdentifiersInOneTranslationUnit(count string) string {
 		addition += " + " + varName
 	}
 
-	content += "\n\tstd::cout << " + addition + " << std::endl;\n}\n"
+	content += "\n\t" + printLine(addition) + "\n}\n"
 
 	for i := 0; i < requiredCount; i++ {
 		varName := "v" + strconv.Itoa(i)
@@ -390,13 +420,13 @@ func macroCountInOneTranslationUnit(count string) string {
 
 	requiredMacroCnt, _ := strconv.Atoi(count)
 
-	content := iostream + "\n#define V0 1\n"
+	content := outputHeader() + "\n#define V0 1\n"
 
 	for i := 1; i < requiredMacroCnt; i++ {
 		content += "#define V" + strconv.Itoa(i) + " V" + strconv.Itoa(i-1) + " + 1\n"
 	}
 
-	content += "\nint main() { std::cout << V" + strconv.Itoa(requiredMacroCnt-1) + "<< std::endl;\n}\n"
+	content += "\nint main() { " + printLine("V"+strconv.Itoa(requiredMacroCnt-1)) + "\n}\n"
 
 	return writeTestFile(trace(), count, content)
 }
@@ -432,11 +462,11 @@ func parametersInMacroDefinition(count string) string {
 		if i < requiredBaseCnt-1 {
 			content += ", "
 		} else {
-			content += ");\n\tstd::cout << v << std::endl;\n}\n"
+			content += ");\n\t" + printLine("v") + "\n}\n"
 		}
 	}
 
-	return writeTestFile(trace(), count, iostream+content)
+	return writeTestFile(trace(), count, outputHeader()+content)
 }
 
 //
@@ -470,8 +500,14 @@ func charactersInOneLogicalSourceLine(count string) string {
 // (2.16) Characters in a string literal ([lex.string]) (after concatenation ([lex.phases])) [65 536].
 //
 func charactersInAStringLiteral(count string) string {
-	content := iostream
-	content += "#include <cstring>\n"
+	content := outputHeader()
+	length := "std::strlen(a)"
+	if language() == cLanguage {
+		content += "#include <string.h>\n"
+		length = "(int)strlen(a)"
+	} else {
+		content += "#include <cstring>\n"
+	}
 	requiredCount, _ := strconv.Atoi(count)
 	content += "int main() {\n"
 	content += "const char* a=\"\\\n"
@@ -482,9 +518,9 @@ func charactersInAStringLiteral(count string) string {
 			cctr = 0
 			content += "\\\n"
 		}
-		content += string(rune(97 + rand.Intn(26)))
+		content += string(rune(97 + random.Intn(26)))
 	}
-	content += "\";\n\tstd::cout << std::strlen(a) << std::endl;\n}\n"
+	content += "\";\n\t" + printLine(length) + "\n}\n"
 	return writeTestFile(trace(), count, content)
 }
 
@@ -512,9 +548,9 @@ func nestingLevelsForIncludes(count string) string {
 	content += "#include \"inc/header1.h\"\n"
 	requiredCount, _ := strconv.Atoi(count)
 	for i := 1; i < requiredCount; i++ {
-		writeHeaderFile(i, "#include \"header"+strconv.Itoa(i+1)+".h\"\n")
+		writeHeaderFile("header", i, "#include \"header"+strconv.Itoa(i+1)+".h\"\n")
 	}
-	writeHeaderFile(requiredCount, "const int v = "+strconv.Itoa(requiredCount)+";\n")
+	writeHeaderFile("header", requiredCount, "const int v = "+strconv.Itoa(requiredCount)+";\n")
 
 	content += "int main() {\n"
 	content += "\tstd::cout << v << std::endl;\n}\n"
@@ -527,13 +563,17 @@ func nestingLevelsForIncludes(count string) string {
 func caseLabelsForSwitch(count string) string {
 	requiredLabelCnt, _ := strconv.Atoi(count)
 
-	content := "#include<ctime>\n#include<cstdlib>\n\nint main() {\n\tsrand(time(NULL));\tint v = rand() % " + count + " + 1;\n\tswitch(v) {\n"
+	content := "#include<ctime>\n#include<cstdlib>\n"
+	if language() == cLanguage {
+		content = "#include <time.h>\n#include <stdlib.h>\n"
+	}
+	content += "\nint main() {\n\tsrand(time(NULL));\tint v = rand() % " + count + " + 1;\n\tswitch(v) {\n"
 	for i := 0; i < requiredLabelCnt; i++ {
-		content += "\t\tcase " + strconv.Itoa(i) + ": std::cout << " + strconv.Itoa(i*i) + " << std::endl; break;\n"
+		content += "\t\tcase " + strconv.Itoa(i) + ": " + printLine(strconv.Itoa(i*i)) + " break;\n"
 	}
 	content += "}\n}\n"
 
-	return writeTestFile(trace(), count, iostream+content)
+	return writeTestFile(trace(), count, outputHeader()+content)
 }
 
 //
@@ -605,10 +645,14 @@ func enumerationConstantsInEnum(count string) string {
 
 	content := "\n#include<cstdlib>\n\n enum Stuff {"
 	for i := 0; i < requiredEnumCnt; i++ {
-		content += "\t\tV" + strconv.Itoa(i) + " = " + strconv.Itoa(i) + ",\n"
+		content += "\t\tV" + strconv.Itoa(i) + " = " + strconv.Itoa(i)
+		if i < requiredEnumCnt-1 {
+			content += ","
+		}
+		content += "\n"
 	}
 
-	content += "};\nint main() {\nStuff v = V" + strconv.Itoa(rand.Intn(requiredEnumCnt))
+	content += "};\nint main() {\nStuff v = V" + strconv.Itoa(random.Intn(requiredEnumCnt))
 	content += ";\nstd::cout << v << std::endl;\n}\n"
 
 	return writeTestFile(trace(), count, iostream+content)
@@ -1106,6 +1150,54 @@ func fullExpressionInAConst(count string) string {
 	return writeTestFile(trace(), count, content)
 }
 
+//
+// Steps of one constant evaluation ([expr.const]), the calls of a constexpr function splitting the count in two
+// halves until it reaches 1, so neither the depth of the recursion nor a loop limits the evaluation. The halves are
+// passed their offset too, so the compilers cannot reuse the results of the calls with the same arguments. Not a
+// limit of annex B.
+//
+func constexprEvaluationSteps(count string) string {
+	content := iostream + "constexpr long long leaves(long long offset, long long n) {\n" +
+		"\treturn n <= 1 ? n : leaves(offset, n / 2) + leaves(offset + n / 2, n - n / 2);\n}\n\n" +
+		"constexpr long long k = leaves(0, " + count + ");\n\n" +
+		"int main() {\n\tstd::cout << k << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Iterations of one loop in a constant evaluation ([expr.const]). Not a limit of annex B, C++14.
+//
+func constexprLoopIterations(count string) string {
+	content := iostream + "constexpr long long loop(long long n) {\n" +
+		"\tlong long s = 0;\n\tfor (long long i = 0; i < n; ++i)\n\t\t++s;\n\treturn s;\n}\n\n" +
+		"constexpr long long k = loop(" + count + ");\n\n" +
+		"int main() {\n\tstd::cout << k << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Elements of an array allocated by new in a constant evaluation ([expr.const], [expr.new]). Not a limit of annex B,
+// C++20.
+//
+func constexprAllocationSize(count string) string {
+	content := iostream + "constexpr int allocate(int n) {\n" +
+		"\tint *p = new int[n]();\n\tp[n - 1] = n;\n\tint last = p[n - 1];\n\tdelete[] p;\n\treturn last;\n}\n\n" +
+		"constexpr int k = allocate(" + count + ");\n\n" +
+		"int main() {\n\tstd::cout << k << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Recursive invocations of an immediate function ([dcl.constexpr]), the consteval counterpart of recursiveConstexpr.
+// Not a limit of annex B, C++20.
+//
+func constevalCallDepth(count string) string {
+	content := iostream + "consteval unsigned long long sum(unsigned long long n, unsigned long long s = 0) {\n" +
+		"\treturn n ? sum(n - 1, s + n) : s;\n}\n\n" +
+		"int main() {\n\tstd::cout << sum(" + count + ") << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
 //
 // (2.40) Template parameters in a template declaration ([temp.param]) [1 024].
 //
@@ -1154,6 +1246,83 @@ func recursivelyNestedTemplateInstantiations(count string) string {
 	return writeTestFile(trace(), count, content)
 }
 
+//
+// Elements of a pack expanded from std::make_index_sequence ([temp.variadic], [intseq]). Not a limit of annex B.
+//
+func elementsInPackExpansion(count string) string {
+	content := iostream + "#include <utility>\n\n" +
+		"template<std::size_t... I>\nint expand(std::index_sequence<I...>) {\n" +
+		"\tint values[] = {static_cast<int>(I)...};\n\treturn values[sizeof(values) / sizeof(values[0]) - 1] + 1;\n}\n\n" +
+		"int main() {\n\tstd::cout << expand(std::make_index_sequence<" + count + ">()) << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Operands of one fold expression ([expr.prim.fold]), the elements of the pack of the arguments of a call. Not a
+// limit of annex B.
+//
+func operandsInFoldExpression(count string) string {
+	requiredCount, _ := strconv.Atoi(count)
+	content := iostream + "template<typename... T>\nconstexpr int sum(T... t) {\n\treturn (t + ...);\n}\n\n" +
+		"int main() {\n\tconstexpr int s = sum(" + strings.TrimSuffix(repeat("1, ", requiredCount), ", ") + ");\n" +
+		"\tstd::cout << s << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// A pack expansion nested into the pattern of another one, expanding count * count elements ([temp.variadic]). Not
+// a limit of annex B, the count is the size of the packs.
+//
+func nestedPackExpansions(count string) string {
+	content := iostream + "#include <utility>\n\n" +
+		"template<std::size_t... I>\nstruct Outer {\n\ttemplate<std::size_t... J>\n" +
+		"\tstatic constexpr std::size_t value(std::index_sequence<J...>) {\n" +
+		"\t\treturn ((I * 0 + (... + (J * 0 + 1))) + ...);\n\t}\n};\n\n" +
+		"template<std::size_t... I>\nconstexpr std::size_t nested(std::index_sequence<I...> s) {\n" +
+		"\treturn Outer<I...>::value(s);\n}\n\n" +
+		"int main() {\n\tstd::cout << nested(std::make_index_sequence<" + count + ">()) << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Types in the pack counted by one sizeof... ([expr.sizeof]). Not a limit of annex B.
+//
+func typesInSizeofPack(count string) string {
+	content := iostream + "#include <type_traits>\n#include <utility>\n\n" +
+		"template<typename... T>\nstruct Count {\n\tstatic constexpr std::size_t value = sizeof...(T);\n};\n\n" +
+		"template<std::size_t... I>\nconstexpr std::size_t count(std::index_sequence<I...>) {\n" +
+		"\treturn Count<std::integral_constant<std::size_t, I>...>::value;\n}\n\n" +
+		"int main() {\n\tstd::cout << count(std::make_index_sequence<" + count + ">()) << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Elements of a std::tuple ([tuple]). Not a limit of annex B.
+//
+func elementsInTuple(count string) string {
+	requiredCount, _ := strconv.Atoi(count)
+	content := iostream + "#include <tuple>\n\n" +
+		"int main() {\n\tstd::tuple<" + strings.TrimSuffix(repeat("int, ", requiredCount), ", ") + "> t;\n" +
+		"\tstd::get<" + strconv.Itoa(requiredCount-1) + ">(t) = " + count + ";\n" +
+		"\tstd::cout << std::get<" + strconv.Itoa(requiredCount-1) + ">(t) << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Alternatives of a std::variant ([variant]), each one a different type. Not a limit of annex B.
+//
+func alternativesInVariant(count string) string {
+	requiredCount, _ := strconv.Atoi(count)
+	alternatives := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		alternatives = append(alternatives, "std::integral_constant<int, "+strconv.Itoa(i+1)+">")
+	}
+	content := iostream + "#include <type_traits>\n#include <variant>\n\n" +
+		"int main() {\n\tstd::variant<" + strings.Join(alternatives, ", ") + "> v(std::in_place_index<" + strconv.Itoa(requiredCount-1) + ">);\n" +
+		"\tstd::cout << std::visit([](auto a) { return a.value; }, v) << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content)
+}
+
 //
 // (2.42) Handlers per try block ([except.handle]) [256].
 //
@@ -1233,25 +1402,548 @@ func numberOfPlaceholders(count string) string {
 	return writeTestFile(trace(), count, content)
 }
 
+//
+// Translation units defining extern identifiers, linked into one executable. Not a limit of annex B, the count is
+// the number of translation units besides the one of main.
+//
+func externIdentifiersInTranslationUnits(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	declarations := ""
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		units = append(units, writeUnitFile(trace(), count, i, sourceUnit, "int v"+idx+" = 1;\nint f"+idx+"() { return v"+idx+"; }\n"))
+		declarations += "extern int v" + idx + ";\nint f" + idx + "();\n"
+		functions = append(functions, "f"+idx)
+	}
+
+	content := iostream + declarations + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// Translation units sharing the same inline functions and template instantiations, which the linker folds into one
+// copy each (COMDAT). Not a limit of annex B, the count is the number of translation units besides the one of main.
+//
+func inlineFunctionsInTranslationUnits(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	writeIncludeFile("shared.h", "#pragma once\n\n"+
+		"inline int shared(int i) { return i; }\n"+
+		"template<typename T> T twice(T t) { return t + t; }\n"+
+		"template<int N> struct Counter { static int get() { return Counter<N - 1>::get() + shared(1); } };\n"+
+		"template<> struct Counter<0> { static int get() { return 0; } };\n")
+
+	declarations := ""
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		units = append(units, writeUnitFile(trace(), count, i, sourceUnit, "#include \"inc/shared.h\"\n\n"+
+			"int unit"+idx+"() { return Counter<64>::get() / twice(32); }\n"))
+		declarations += "int unit" + idx + "();\n"
+		functions = append(functions, "unit"+idx)
+	}
+
+	content := iostream + "#include \"inc/shared.h\"\n\n" + declarations + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// Object files archived into one static library linked with main. Not a limit of annex B, the count is the number
+// of object files in the library.
+//
+func objectFilesInStaticLibrary(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	declarations := ""
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		units = append(units, writeUnitFile(trace(), count, i, libraryUnit, "int library"+idx+"() { return 1; }\n"))
+		declarations += "int library" + idx + "();\n"
+		functions = append(functions, "library"+idx)
+	}
+
+	content := iostream + declarations + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// A chain of derived classes whose virtual functions, and so whose vtables, are defined in a translation unit for
+// each class. Not a limit of annex B, the count is the number of classes.
+//
+func virtualFunctionsInTranslationUnits(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	header := "#pragma once\n\nstruct C0 {\n\tvirtual ~C0();\n\tvirtual int f() const;\n};\n"
+	for i := 1; i < requiredCount; i++ {
+		header += "struct C" + strconv.Itoa(i) + " : C" + strconv.Itoa(i-1) + " {\n\tint f() const override;\n};\n"
+	}
+	headerName := trace() + "-" + count + ".h"
+	writeIncludeFile(headerName, header)
+
+	units = append(units, writeUnitFile(trace(), count, 0, sourceUnit, "#include \"inc/"+headerName+"\"\n\nC0::~C0() {}\nint C0::f() const { return 1; }\n"))
+	for i := 1; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		units = append(units, writeUnitFile(trace(), count, i, sourceUnit, "#include \"inc/"+headerName+"\"\n\n"+
+			"int C"+idx+"::f() const { return C"+strconv.Itoa(i-1)+"::f() + 1; }\n"))
+	}
+
+	content := iostream + "#include \"inc/" + headerName + "\"\n\n" +
+		"int main() {\n\tC" + strconv.Itoa(requiredCount-1) + " c;\n\tconst C0 &base = c;\n" +
+		"\tstd::cout << base.f() << std::endl;\n}\n"
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// (5.2.4.1) 12 pointer, array, and function declarators (in any combination) modifying an arithmetic, structure,
+// union, or void type in a declaration. A limit of the C standard, the declarators are pointers.
+//
+func declaratorsModifyingAType(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader() + "int main(void) {\n\tint p0 = " + count + ";\n"
+	for i := 1; i <= requiredCount; i++ {
+		content += "\tint " + repeat("*", i) + "p" + strconv.Itoa(i) + " = &p" + strconv.Itoa(i-1) + ";\n"
+	}
+	content += "\t" + printLine(repeat("*", requiredCount)+"p"+count) + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// (5.2.4.1) 1023 members in a single structure or union. A limit of the C standard.
+//
+func membersInAStructure(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader() + "struct S {\n"
+	for i := 0; i < requiredCount; i++ {
+		content += "\tint m" + strconv.Itoa(i) + ";\n"
+	}
+	last := "s.m" + strconv.Itoa(requiredCount-1)
+	content += "};\n\nint main(void) {\n\tstatic struct S s;\n\t" + last + " = " + count + ";\n\t" + printLine(last) + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// (5.2.4.1) 63 levels of nested structure or union definitions in a single struct-declaration-list. A limit of the
+// C standard.
+//
+func nestingOfStructureDefinitions(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader()
+	member := "s"
+	for i := 0; i < requiredCount; i++ {
+		content += repeat("\t", i) + "struct S" + strconv.Itoa(i) + " {\n"
+		if i > 0 {
+			member += ".m" + strconv.Itoa(i)
+		}
+	}
+	content += repeat("\t", requiredCount) + "int v;\n"
+	for i := requiredCount - 1; i > 0; i-- {
+		content += repeat("\t", i) + "} m" + strconv.Itoa(i) + ";\n"
+	}
+	member += ".v"
+	content += "};\n\nint main(void) {\n\tstatic struct S0 s;\n\t" + member + " = " + count + ";\n\t" + printLine(member) + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Conjunctions of concepts in one requires-clause ([temp.pre], [temp.constr.op]). Not a limit of annex B, C++20.
+//
+func conjunctionsInOneRequiresClause(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := iostream
+	constraints := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		content += "template<typename T>\nconcept C" + strconv.Itoa(i) + " = sizeof(T) > 0;\n"
+		constraints = append(constraints, "C"+strconv.Itoa(i)+"<T>")
+	}
+	content += "\ntemplate<typename T>\n\trequires " + strings.Join(constraints, " && ") + "\n" +
+		"int f(T) {\n\treturn " + count + ";\n}\n\nint main() {\n\tstd::cout << f(1) << std::endl;\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Nesting levels of requires-expressions, each one a nested requirement of the enclosing one ([expr.prim.req]). Not
+// a limit of annex B, C++20.
+//
+func nestingOfRequiresExpressions(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	expression := "requires (T t) { t + 1; }"
+	for i := 1; i < requiredCount; i++ {
+		expression = "requires (T t) { requires " + expression + "; }"
+	}
+	content := iostream + "template<typename T>\nconcept C = " + expression + ";\n\n" +
+		"int main() {\n\tstd::cout << (C<int> ? " + count + " : 0) << std::endl;\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Concepts refining each other, the overload constrained by each of them taking part in the same overload
+// resolution, which selects the most constrained one by the subsumption of the constraints ([temp.constr.order]).
+// Not a limit of annex B, C++20.
+//
+func conceptsSubsumedInOverloadResolution(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := iostream + "template<typename T>\nconcept C0 = sizeof(T) > 0;\n"
+	for i := 1; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		content += "template<typename T>\nconcept A" + idx + " = sizeof(T) > 0;\n" +
+			"template<typename T>\nconcept C" + idx + " = C" + strconv.Itoa(i-1) + "<T> && A" + idx + "<T>;\n"
+	}
+	content += "\n"
+	for i := 0; i < requiredCount; i++ {
+		content += "template<C" + strconv.Itoa(i) + " T>\nint f(T) {\n\treturn " + strconv.Itoa(i+1) + ";\n}\n"
+	}
+	content += "\nint main() {\n\tstd::cout << f(1) << std::endl;\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Overloads of a function template with mutually exclusive constraints in one overload set, the one whose
+// constraints are satisfied being selected ([over.match.viable]). Not a limit of annex B, C++20.
+//
+func constrainedOverloadsInOneOverloadSet(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := iostream
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		content += "template<int N>\n\trequires (N == " + idx + ")\nint f() {\n\treturn " + strconv.Itoa(i+1) + ";\n}\n"
+	}
+	content += "\nint main() {\n\tstd::cout << f<" + strconv.Itoa(requiredCount-1) + ">() << std::endl;\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Module interface units imported by one translation unit ([module.import]). Not a limit of annex B, C++20, the
+// count is the number of modules.
+//
+func moduleInterfacesImportedByOneTranslationUnit(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	imports := ""
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		module := trace() + "_" + count + "_" + idx
+		units = append(units, writeModuleFile(trace(), count, i, module, "export module "+module+";\n\nexport int f"+idx+"() { return 1; }\n"))
+		imports += "import " + module + ";\n"
+		functions = append(functions, "f"+idx)
+	}
+
+	content := iostream + imports + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// Partitions of one module ([module.unit]), all of them exported by its primary interface. Not a limit of annex B,
+// C++20, the count is the number of partitions.
+//
+func partitionsInOneModule(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	module := trace() + "_" + count
+	primary := "export module " + module + ";\n\n"
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		partition := module + ":p" + idx
+		units = append(units, writeModuleFile(trace(), count, i, partition, "export module "+partition+";\n\nexport int f"+idx+"() { return 1; }\n"))
+		primary += "export import :p" + idx + ";\n"
+		functions = append(functions, "f"+idx)
+	}
+	units = append(units, writeModuleFile(trace(), count, requiredCount, module, primary))
+
+	content := iostream + "import " + module + ";\n" + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// Declarations exported by one module ([module.interface]). Not a limit of annex B, C++20.
+//
+func exportedDeclarationsInOneModule(count string) (string, []translationUnit) {
+
+	requiredCount, _ := strconv.Atoi(count)
+	units := make([]translationUnit, 0, requiredCount)
+
+	module := trace() + "_" + count
+	exports := "export module " + module + ";\n\n"
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		exports += "export int f" + idx + "() { return 1; }\n"
+		functions = append(functions, "f"+idx)
+	}
+	units = append(units, writeModuleFile(trace(), count, 0, module, exports))
+
+	content := iostream + "import " + module + ";\n" + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content), units
+}
+
+//
+// Recursion emulated by the preprocessor the way the preprocessor metaprogramming libraries do it: a macro deferring
+// its own invocation, rescanned by a chain of EVAL macros until its count reaches 0. Not a limit of annex B, the
+// count is the depth of the recursion and of the chain.
+//
+func evalMacroRecursionDepth(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader() +
+		"#define EMPTY()\n#define DEFER(id) id EMPTY()\n#define OBSTRUCT(...) __VA_ARGS__ DEFER(EMPTY)()\n" +
+		"#define CAT(a, ...) CAT_(a, __VA_ARGS__)\n#define CAT_(a, ...) a ## __VA_ARGS__\n" +
+		"#define IIF(c) CAT(IIF_, c)\n#define IIF_0(t, ...) __VA_ARGS__\n#define IIF_1(t, ...) t\n\n#define BOOL_0 0\n"
+	for i := 1; i <= requiredCount; i++ {
+		content += "#define BOOL_" + strconv.Itoa(i) + " 1\n#define DEC_" + strconv.Itoa(i) + " " + strconv.Itoa(i-1) + "\n"
+	}
+	content += "\n#define SUM_INDIRECT() SUM\n#define SUM(n) IIF(CAT(BOOL_, n))(+ 1 OBSTRUCT(SUM_INDIRECT)()(CAT(DEC_, n)), )\n\n" +
+		"#define EVAL0(...) __VA_ARGS__\n"
+	for i := 1; i < requiredCount; i++ {
+		content += "#define EVAL" + strconv.Itoa(i) + "(...) EVAL" + strconv.Itoa(i-1) + "(__VA_ARGS__)\n"
+	}
+	content += "\nint main(void) {\n\t" + printLine("0 EVAL"+strconv.Itoa(requiredCount-1)+"(SUM("+count+"))") + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Arguments of one invocation of a variadic macro, all of them passed on as __VA_ARGS__ ([cpp.replace]). Not a limit
+// of annex B, which counts the arguments of the macros with named parameters only.
+//
+func argumentsInVariadicMacro(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader() + "#define VALUES(...) { __VA_ARGS__ }\n\n" +
+		"int main(void) {\n\tint values[] = VALUES(" + strings.TrimSuffix(repeat("1, ", requiredCount), ", ") + ");\n" +
+		"\t" + printLine("(int)(sizeof(values) / sizeof(values[0]))") + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Nesting levels of invocations of variadic macros using __VA_OPT__ ([cpp.subst]), each one adding 1 if it has
+// arguments. Not a limit of annex B, C++20.
+//
+func nestingOfVaOptExpansions(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader() + "#define V0(...) 0 __VA_OPT__(+ 1)\n"
+	for i := 1; i < requiredCount; i++ {
+		content += "#define V" + strconv.Itoa(i) + "(...) V" + strconv.Itoa(i-1) + "(__VA_ARGS__) __VA_OPT__(+ 1)\n"
+	}
+	content += "\nint main(void) {\n\t" + printLine("V"+strconv.Itoa(requiredCount-1)+"(x)") + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Token pasting operators (##) chained in one replacement list ([cpp.concat]), pasting the parameter to itself into
+// an identifier of count characters. Not a limit of annex B.
+//
+func pasteOperatorsInOneReplacement(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	content := outputHeader() + "#define PASTE(a) " + strings.TrimSuffix(repeat("a ## ", requiredCount), " ## ") + "\n\n" +
+		"int PASTE(p) = " + count + ";\n\nint main(void) {\n\t" + printLine("PASTE(p)") + "\n}\n"
+
+	return writeTestFile(trace(), count, content)
+}
+
+//
+// Distinct headers included by one translation unit ([cpp.include]), the breadth of the includes rather than their
+// nesting. Not a limit of annex B.
+//
+func distinctHeadersIncluded(count string) string {
+
+	requiredCount, _ := strconv.Atoi(count)
+
+	prefix := trace() + "-" + count + "-"
+	includes := ""
+	functions := make([]string, 0, requiredCount)
+	for i := 0; i < requiredCount; i++ {
+		idx := strconv.Itoa(i)
+		writeHeaderFile(prefix, i, "#pragma once\n\ninline int f"+idx+"() { return 1; }\n")
+		includes += "#include \"inc/" + prefix + idx + ".h\"\n"
+		functions = append(functions, "f"+idx)
+	}
+
+	content := iostream + includes + mainSummingFunctions(functions)
+	return writeTestFile(trace(), count, content)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 //                                                   Main                                                             //
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// the subcommands of the application, the first argument selects one of them, generate being the default
+var commands = map[string]func(args []string){
+	"generate": generateCommand,
+	"run":      runCommand,
+	"reduce":   reduceCommand,
+	"history":  historyCommand,
+}
+
 func main() {
-	dat, err := ioutil.ReadFile("testset.json")
+	command := "generate"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+
+	if _, ok := commands[command]; !ok {
+		fmt.Println("unknown command:", command)
+		os.Exit(2)
+	}
+	commands[command](args)
+
+	// the output of the queries is kept clean for the other tools reading it
+	if command != "history" {
+		fmt.Println("Done")
+	}
+}
+
+// creates the flags of a subcommand, with the flags common to all of them
+func commandFlags(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	config := flags.String("config", "testset.json", "the json file describing the test set")
+	return flags, config
+}
+
+// generates the tests and the build files
+func generateCommand(args []string) {
+	flags, config := commandFlags("generate")
+	flags.Parse(args)
+
+	loadTestSet(*config)
+	_, testPath := generateTestSet()
+	writeManifest(newManifest(testPath), testPath)
+}
+
+// generates the tests, then runs the tools on them
+func runCommand(args []string) {
+	flags, config := commandFlags("run")
+	fromManifest := flags.String("from-manifest", "", "reproduce the run of the manifest, instead of the json file")
+	flags.Parse(args)
+
+	var expected *Manifest
+	if len(*fromManifest) > 0 {
+		// read before generating, the manifest may be in the directory of the test set
+		expected = readManifest(*fromManifest)
+		loadManifestTestSet(expected)
+	} else {
+		loadTestSet(*config)
+	}
+
+	tests, testPath := generateTestSet()
+	manifest := newManifest(testPath)
+	if expected != nil {
+		if mismatches := verifyManifest(expected, manifest); mismatches > 0 {
+			fmt.Println(mismatches, "generated files differ from the manifest, the run cannot be reproduced")
+			os.Exit(1)
+		}
+		fmt.Println("Verified:", len(manifest.Artifacts), "generated files match the manifest")
+	}
+	run := runTests(tests, testPath, manifest)
+	if expected != nil {
+		compareCompilers(expected, run.Compilers)
+	}
+}
+
+// loads the test set from the json file
+func loadTestSet(fileName string) {
+	dat, err := ioutil.ReadFile(fileName)
 	check(err)
+	parseTestSet(fileName, dat)
+}
+
+// parses the content of the json file of the test set, picks a seed for the generators if none is given
+func parseTestSet(fileName string, dat []byte) {
+	testSet = TestSet{}
 	jsonErr := json.Unmarshal(dat, &testSet)
 	if jsonErr != nil {
-		fmt.Println("error:", err)
+		fmt.Println("error:", jsonErr)
 		panic(jsonErr)
 	}
+	if err := validateTools(); err != nil {
+		fmt.Println("error:", err)
+		panic(err)
+	}
+	testSetFile, testSetContent = fileName, dat
+	if testSet.Seed == 0 {
+		testSet.Seed = time.Now().UnixNano()
+	}
+}
 
-	makefileHeader := "CXX="
-	if len(testSet.Compiler) > 0 {
-		makefileHeader += testSet.Compiler
-	} else {
-		makefileHeader += "g++"
+// generates the sources of the test for the given count
+func generateTest(entry *TestEntry, count string) generatedTest {
+	generator, ok := funcMap[entry.TestName]
+	if !ok {
+		panic("unknown test: " + entry.TestName)
+	}
+	if !hasLanguageVersion(entry.TestName) {
+		panic("test " + entry.TestName + " cannot be generated in " + language())
+	}
+	seedGenerator(entry.TestName + "-" + count)
+	var fileName string
+	var units []translationUnit
+	switch generator := generator.(type) {
+	case func(string) string:
+		fileName = generator(count)
+	case func(string) (string, []translationUnit):
+		fileName, units = generator(count)
+	default:
+		panic("invalid generator for test: " + entry.TestName)
+	}
+	return generatedTest{entry.TestName + "-" + count, entry, count, filepath.Base(fileName), units}
+}
+
+// generates the tests of the test set and the requested build files, returns the tests and their directory
+func generateTestSet() ([]generatedTest, string) {
+	compilerVariable, flagsVariable := makeVariables()
+	makefileHeader := compilerVariable + "=" + compilerName()
+	makefileHeader += "\n" + flagsVariable + "=" + familyFlags() + "\n"
+	makefileHeader += raisedLimitsSwitch + "?="
+	if buildFilesRaiseLimits() {
+		makefileHeader += "1"
 	}
-	makefileHeader += "\nCXXFLAGS=" + testSet.CompilerFlags + "\n\n"
+	makefileHeader += "\n\n"
 	makefileContent := ""
 
 	//fmt.Printf("Tests: %+v ", testSet)
@@ -1269,28 +1961,39 @@ func main() {
 
 	all := "all: "
 	clean := "clean: \n"
+	libraryRules := ""
 
-	fileNames := make([]string, 0)
-
-	cmakeContent := "cmake_minimum_required(VERSION 2.8.9)\n\n" + "project(" + testSet.SetName + ")\n\n"
+	generated := make([]generatedTest, 0)
 
 	for i := 0; i < len(testSet.Tests); i++ {
+		if testSet.Tests[i].Run && !hasLanguageVersion(testSet.Tests[i].TestName) {
+			fmt.Println("Skipping:", testSet.Tests[i].TestName, "cannot be generated in", language())
+			continue
+		}
 		if testSet.Tests[i].Run {
 			for cnt := 0; cnt < len(testSet.Tests[i].Count); cnt++ {
 				currentCount := testSet.Tests[i].Count[cnt]
-				fileName := funcMap[testSet.Tests[i].TestName].(func(string) string)(currentCount)
-				fileName = filepath.Base(fileName)
-
-				fileNames = append(fileNames, fileName)
+				test := generateTest(&testSet.Tests[i], currentCount)
+				fileName := test.fileName
 
-				currentTestName := testSet.Tests[i].TestName + "-" + currentCount
+				currentTestName := test.name
+				generated = append(generated, test)
 				fmt.Println("Running:", currentTestName, time.Now().Format(time.RFC3339Nano))
 
-				if runtime.GOOS != "windows" {
+				if family().makefile {
 
 					if testSet.GenerateMakefile {
 
-						makefileContent += testSet.Tests[i].TestName + "-" + currentCount + ": " + fileName + "\n"
+						// the sources of a test made of several translation units are compiled and linked together
+						sources := append(test.sources(sourceUnit), test.moduleObjects(familyName())...)
+						prerequisites := append(test.sources(sourceUnit), test.moduleObjects(familyName())...)
+						if len(test.unitFiles(libraryUnit)) > 0 {
+							sources = append(sources, test.libraryName(familyName()))
+							prerequisites = append(prerequisites, test.libraryName(familyName()))
+						}
+						fileName = strings.Join(sources, " ")
+
+						makefileContent += testSet.Tests[i].TestName + "-" + currentCount + ": " + strings.Join(prerequisites, " ") + "\n"
 
 						if testSet.TimedCompilation {
 							if testSet.ResultFormat == "XML" {
@@ -1309,7 +2012,7 @@ func main() {
 							if testSet.TimedCompilation {
 								makefileContent += "/usr/bin/time " + testSet.TimeFlags + " "
 							}
-							makefileContent += "$(CXX) $(CXXFLAGS) -o " + testSet.Tests[i].TestName + "-" + currentCount + " " + fileName + "; \\\n\tdone"
+							makefileContent += makeCompileCommand(test) + "-o " + testSet.Tests[i].TestName + "-" + currentCount + " " + fileName + "; \\\n\tdone"
 							if testSet.TimedCompilation {
 								if testSet.ResultFormat == "XML" {
 									makefileContent += "\\\n\techo '</test>';"
@@ -1321,34 +2024,33 @@ func main() {
 							if testSet.TimedCompilation {
 								makefileContent += "/usr/bin/time " + testSet.TimeFlags + " "
 							}
-							makefileContent += "$(CXX) $(CXXFLAGS) -o " + testSet.Tests[i].TestName + "-" + currentCount + " " + fileName + "\n\n"
+							makefileContent += makeCompileCommand(test) + "-o " + testSet.Tests[i].TestName + "-" + currentCount + " " + fileName + "\n\n"
 						}
 						all += testSet.Tests[i].TestName + "-" + currentCount + " "
 
 						clean += "\trm " + testSet.Tests[i].TestName + "-" + currentCount + "\n"
+						if len(test.unitFiles(libraryUnit)) > 0 {
+							libraryRules += makefileLibraryRules(test)
+							clean += "\trm " + test.libraryName(familyName()) + " " + strings.Join(libraryObjects(familyName(), test), " ") + "\n"
+						}
+						if len(test.modules()) > 0 {
+							libraryRules += makefileModuleRules(test)
+							clean += "\trm " + strings.Join(test.moduleObjects(familyName()), " ") + "\n"
+						}
 					}
 				}
-
-				if testSet.GenerateCMakeListsTxt {
-					cmakeContent += "# " + currentTestName + "\n"
-					if runtime.GOOS == "windows" {
-						cmakeContent += "if (1)\n  set_source_files_properties(" + fileName + " PROPERTIES COMPILE_FLAGS /constexpr:depth16384)\n  set_source_files_properties(" + fileName + " PROPERTIES COMPILE_FLAGS /bigobj)\n  set_source_files_properties(" + fileName + " PROPERTIES COMPILE_FLAGS /std:c++17)\nendif()\n"
-					}
-
-					cmakeContent += "add_executable(" + currentTestName + " " + fileName + " )\n\n"
-				}
 			}
 		}
 	}
 
-	if runtime.GOOS != "windows" {
+	if family().makefile {
 		if testSet.GenerateMakefile {
 			makefileName := dir + "/" + testSet.SetName + "/Makefile"
 			f, err := os.Create(makefileName)
 			check(err)
 			defer f.Close()
 
-			f.WriteString(makefileHeader + "\n" + all + "\n\n" + makefileContent + "\n\n" + clean + "\n")
+			f.WriteString(makefileHeader + "\n" + all + "\n\n" + makefileContent + libraryRules + "\n\n" + clean + "\n")
 		}
 	}
 
@@ -1358,8 +2060,17 @@ func main() {
 		check(err)
 		defer f.Close()
 
-		f.WriteString(cmakeContent)
+		f.WriteString(cmakeListsContent(generated))
+	}
+
+	if testSet.GenerateCompileCmds {
+		compileCommandsName := testPath + "/compile_commands.json"
+		f, err := os.Create(compileCommandsName)
+		check(err)
+		defer f.Close()
+
+		f.WriteString(compileCommandsContent(generated, testPath))
 	}
 
-	fmt.Println("Done")
+	return generated, testPath
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the version of cpp-stresstest, set with -ldflags "-X main.version=..." when building a release
+var version = "devel"
+
+// everything needed to reproduce a run: the configuration, the seed of the generators, the machine, the compilers
+// and the hashes of the generated files
+type Manifest struct {
+	ToolVersion string             `json:"toolVersion"`
+	Time        string             `json:"time"`
+	ConfigFile  string             `json:"configFile"`
+	ConfigHash  string             `json:"configHash"` // SHA-256 of the json file
+	Config      string             `json:"config"`     // the content of the json file
+	Seed        int64              `json:"seed"`
+	Host        HostInfo           `json:"host"`
+	Environment []string           `json:"environment"` // the variables relevant for compilers, in NAME=value format
+	Compilers   []CompilerIdentity `json:"compilers,omitempty"`
+	Artifacts   []Artifact         `json:"artifacts"`
+}
+
+// the machine a run was made on
+type HostInfo struct {
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Hostname string `json:"hostname"`
+	CPUModel string `json:"cpuModel"`
+	CPUCount int    `json:"cpuCount"`
+	MemoryKB int64  `json:"memoryKB"`
+	Kernel   string `json:"kernel"`
+	Distro   string `json:"distro"`
+}
+
+// a generated file with its hash
+type Artifact struct {
+	File   string `json:"file"` // relative to the directory of the test set, with forward slashes
+	SHA256 string `json:"sha256"`
+}
+
+// the json file the test set was loaded from, as it was read
+var (
+	testSetFile    string
+	testSetContent []byte
+)
+
+// the version of cpp-stresstest with the revision it was built from, if known
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version
+	}
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if len(revision) == 0 {
+		return version
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return version + " (" + revision + ")"
+}
+
+// the manifest of the test set generated in dir, without the compilers, which are only known when they are probed
+func newManifest(dir string) *Manifest {
+	hash := sha256.Sum256(testSetContent)
+	return &Manifest{
+		ToolVersion: toolVersion(),
+		Time:        time.Now().Format(time.RFC3339),
+		ConfigFile:  testSetFile,
+		ConfigHash:  hex.EncodeToString(hash[:]),
+		Config:      string(testSetContent),
+		Seed:        testSet.Seed,
+		Host:        hostInfo(),
+		Environment: relevantEnvironment(),
+		Artifacts:   hashArtifacts(dir),
+	}
+}
+
+// writes the manifest into dir
+func writeManifest(manifest *Manifest, dir string) {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	check(err)
+	check(os.WriteFile(filepath.Join(dir, "manifest.json"), content, 0644))
+}
+
+// reads a manifest
+func readManifest(fileName string) *Manifest {
+	content, err := os.ReadFile(fileName)
+	check(err)
+	var manifest Manifest
+	check(json.Unmarshal(content, &manifest))
+	return &manifest
+}
+
+// loads the test set stored in the manifest, with the seed the tests were generated with
+func loadManifestTestSet(manifest *Manifest) {
+	parseTestSet(manifest.ConfigFile, []byte(manifest.Config))
+	testSet.Seed = manifest.Seed
+}
+
+// the files which are not hashed: the compilation database contains the absolute path of the test set, which differs
+// between checkouts, and is derived from the hashed sources and the configuration anyway
+var unhashedArtifacts = map[string]bool{
+	"compile_commands.json": true,
+}
+
+// the hashes of all the files in dir, sorted by name
+func hashArtifacts(dir string) []Artifact {
+	artifacts := make([]Artifact, 0)
+	check(filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		check(err)
+		if info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		check(err)
+		if unhashedArtifacts[filepath.ToSlash(name)] {
+			return nil
+		}
+		f, err := os.Open(path)
+		check(err)
+		defer f.Close()
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		check(err)
+		artifacts = append(artifacts, Artifact{File: filepath.ToSlash(name), SHA256: hex.EncodeToString(h.Sum(nil))})
+		return nil
+	}))
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].File < artifacts[j].File })
+	return artifacts
+}
+
+// compares the artifacts and the compilers of a run with the ones of the manifest it was reproduced from, returns the
+// number of artifacts which differ. Different compilers are only reported, they are what is compared across runs
+func verifyManifest(expected, actual *Manifest) int {
+	hashes := make(map[string]string)
+	for _, a := range actual.Artifacts {
+		hashes[a.File] = a.SHA256
+	}
+
+	mismatches := 0
+	for _, a := range expected.Artifacts {
+		hash, ok := hashes[a.File]
+		switch {
+		case !ok:
+			fmt.Println("Missing:", a.File)
+			mismatches++
+		case hash != a.SHA256:
+			fmt.Println("Mismatch:", a.File)
+			mismatches++
+		}
+		delete(hashes, a.File)
+	}
+	for file := range hashes {
+		fmt.Println("Unexpected:", file)
+		mismatches++
+	}
+
+	if expected.ToolVersion != actual.ToolVersion {
+		fmt.Println("Warning: the manifest was made by", expected.ToolVersion, "this is", actual.ToolVersion)
+	}
+	return mismatches
+}
+
+// reports the compilers which differ from the ones of the manifest
+func compareCompilers(expected *Manifest, compilers []CompilerIdentity) {
+	known := make(map[string]CompilerIdentity)
+	for _, c := range expected.Compilers {
+		known[c.Compiler] = c
+	}
+	for _, c := range compilers {
+		if k, ok := known[c.Compiler]; ok && (k.Version != c.Version || k.Target != c.Target) {
+			fmt.Println("Warning:", c.Compiler, "is", c.Family, c.Version, c.Target, "the manifest has", k.Family, k.Version, k.Target)
+		}
+	}
+}
+
+// the description of the machine, as far as it can be found out
+func hostInfo() HostInfo {
+	host := HostInfo{OS: runtime.GOOS, Arch: runtime.GOARCH, CPUCount: runtime.NumCPU()}
+	host.Hostname, _ = os.Hostname()
+
+	switch runtime.GOOS {
+	case "linux":
+		host.CPUModel = fileField("/proc/cpuinfo", "model name", ":")
+		if memory := strings.TrimSuffix(fileField("/proc/meminfo", "MemTotal", ":"), " kB"); len(memory) > 0 {
+			host.MemoryKB, _ = strconv.ParseInt(strings.TrimSpace(memory), 10, 64)
+		}
+		host.Kernel = readTrimmed("/proc/sys/kernel/osrelease")
+		host.Distro = strings.Trim(fileField("/etc/os-release", "PRETTY_NAME", "="), `"`)
+	case "darwin":
+		host.CPUModel = commandOutput("sysctl", "-n", "machdep.cpu.brand_string")
+		if memory, err := strconv.ParseInt(commandOutput("sysctl", "-n", "hw.memsize"), 10, 64); err == nil {
+			host.MemoryKB = memory / 1024
+		}
+		host.Kernel = commandOutput("uname", "-r")
+		host.Distro = "macOS " + commandOutput("sw_vers", "-productVersion")
+	case "windows":
+		host.CPUModel = os.Getenv("PROCESSOR_IDENTIFIER")
+		host.Kernel = commandOutput("cmd", "/c", "ver")
+	default:
+		host.Kernel = commandOutput("uname", "-r")
+	}
+	return host
+}
+
+// the value of the first line of the file starting with the key, separated from it by sep
+func fileField(fileName, key, sep string) string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, key) {
+			if i := strings.Index(line, sep); i >= 0 {
+				return strings.TrimSpace(line[i+len(sep):])
+			}
+		}
+	}
+	return ""
+}
+
+// the content of the file without the surrounding white space, empty if it cannot be read
+func readTrimmed(fileName string) string {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// the output of the command without the surrounding white space, empty if it fails
+func commandOutput(name string, args ...string) string {
+	output, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
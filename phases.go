@@ -0,0 +1,75 @@
+package main
+
+// the phases of the compilation a test can be stopped after, so the limits of the preprocessor, of the front end and
+// of the back end can be told apart
+const (
+	preprocessPhase = "preprocess" // -E
+	syntaxPhase     = "syntax"     // -fsyntax-only, parsing and semantic analysis
+	compilePhase    = "compile"    // -c, up to the object file
+	linkPhase       = "link"       // the full build of the executable
+
+	// not a phase of its own: the compilation of a module interface unit into an object file and its BMI, run before
+	// the phases of the units importing the module
+	interfacePhase = "interface"
+)
+
+// the phases in the order they are run by a compiler
+var phaseOrder = []string{preprocessPhase, syntaxPhase, compilePhase, linkPhase}
+
+// the flags stopping the compilers of each family after a phase, no flag is needed for a full build
+var phaseFlags = map[string]map[string]string{
+	"gcc":   {preprocessPhase: "-E", syntaxPhase: "-fsyntax-only", compilePhase: "-c", interfacePhase: "-c"},
+	"clang": {preprocessPhase: "-E", syntaxPhase: "-fsyntax-only", compilePhase: "-c", interfacePhase: "-c -x c++-module -fmodule-output"},
+	"icc":   {preprocessPhase: "-E", syntaxPhase: "-fsyntax-only", compilePhase: "-c", interfacePhase: "-c"},
+	"msvc":  {preprocessPhase: "/E", syntaxPhase: "/Zs", compilePhase: "/c", interfacePhase: "/c /interface"},
+}
+
+// the phases the test is run in: the ones of the test, the ones of the test set, or only the full build
+func testPhases(entry *TestEntry) []string {
+	phases := entry.Phases
+	if len(phases) == 0 {
+		phases = testSet.Phases
+	}
+	if len(phases) == 0 {
+		return []string{linkPhase}
+	}
+	for _, phase := range phases {
+		if phaseIndex(phase) < 0 {
+			panic("unknown phase: " + phase)
+		}
+	}
+	return phases
+}
+
+// the position of the phase in phaseOrder, -1 if it is unknown
+func phaseIndex(phase string) int {
+	for i, p := range phaseOrder {
+		if p == phase {
+			return i
+		}
+	}
+	return -1
+}
+
+// the flag stopping a compiler of the family after the phase
+func phaseFlag(familyName, phase string) string {
+	return phaseFlags[familyName][phase]
+}
+
+// the file written by the compiler when it stops after the phase, so the outputs of the phases do not overwrite
+// each other
+func phaseOutput(familyName string, t generatedTest, phase string) string {
+	return phaseOutputOf(familyName, t.name, phase)
+}
+
+// the file written by a compiler of the family when it stops after the phase while compiling the source with the
+// given name (without its extension)
+func phaseOutputOf(familyName, name, phase string) string {
+	switch phase {
+	case preprocessPhase:
+		return name + preprocessedExtension()
+	case compilePhase, interfacePhase:
+		return name + objectExtension(familyName)
+	}
+	return name
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// what to do when the predicted cost of a test exceeds the timeout or the memory limit
+const (
+	warnOverrun   = "warn"
+	refuseOverrun = "refuse"
+)
+
+// the value of the fitted model at n
+func (fit ScalingFit) at(n float64) float64 {
+	for _, model := range scalingModels {
+		if model.name == fit.Model {
+			return predict(model, fit.A, fit.B, n)
+		}
+	}
+	return 0
+}
+
+// the predicted wall time (in seconds) and memory (in kilobytes) of the test at the given count, extrapolated from
+// the successful results of the same test in the same mode, phase and standard found in the history. A prediction
+// which could not be made is zero
+func predictCost(history []historyRecord, test, mode, phase, std string, debugInfo bool, count string) (float64, float64) {
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	results := make([]TestResult, 0)
+	for _, record := range history {
+		if record.Result.Test == test && record.Result.Mode == mode && record.Result.Phase == phase &&
+			record.Result.Std == std && record.Result.Debug == debugInfo {
+			results = append(results, record.Result)
+		}
+	}
+
+	var predictedTime, predictedMemory float64
+	for _, fit := range fitScaling(results) {
+		switch fit.Metric {
+		case "time":
+			predictedTime = fit.at(n)
+		case "memory":
+			predictedMemory = fit.at(n)
+		}
+	}
+	return predictedTime, predictedMemory
+}
+
+// checks the predicted cost against the timeout and the memory limit of the test set, returns the reason why the
+// test should not be run, or the warning about the overrun if the test is run anyway. A prediction growing beyond
+// any number (ie. the exponential model far from the measured counts) is an overrun too
+func checkPredictedCost(name string, predictedTime, predictedMemory float64) (string, string) {
+	reason := ""
+	if testSet.Timeout > 0 && math.IsInf(predictedTime, 1) {
+		reason = fmt.Sprintf("predicted time is infinite, exceeding the timeout of %ds", testSet.Timeout)
+	} else if testSet.Timeout > 0 && predictedTime > float64(testSet.Timeout) {
+		reason = fmt.Sprintf("predicted time %.0fs exceeds the timeout of %ds", predictedTime, testSet.Timeout)
+	}
+	if testSet.MemoryLimit > 0 && predictedMemory > float64(testSet.MemoryLimit)*1024 {
+		if len(reason) > 0 {
+			reason += ", "
+		}
+		if math.IsInf(predictedMemory, 1) {
+			reason += fmt.Sprintf("predicted memory is infinite, exceeding the limit of %dMB", testSet.MemoryLimit)
+		} else {
+			reason += fmt.Sprintf("predicted memory %.0fMB exceeds the limit of %dMB", predictedMemory/1024, testSet.MemoryLimit)
+		}
+	}
+	if len(reason) == 0 {
+		return "", ""
+	}
+
+	if testSet.OverrunPolicy == refuseOverrun {
+		fmt.Println("Refusing:", name, reason)
+		return reason, ""
+	}
+	fmt.Println("Warning:", name, reason)
+	return "", reason
+}
+
+// a prediction as stored in the results, zero if it is not a finite number, which JSON cannot hold
+func finitePrediction(predicted float64) float64 {
+	if math.IsInf(predicted, 0) || math.IsNaN(predicted) {
+		return 0
+	}
+	return predicted
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// the identity of a compiler, as found out by probing it
+type CompilerIdentity struct {
+	Compiler    string `json:"compiler" xml:"compiler,attr"` // the executable, as given in the json file
+	Family      string `json:"family" xml:"family,attr"`
+	Version     string `json:"version" xml:"version,attr"`
+	Target      string `json:"target" xml:"target,attr"`
+	DefaultStd  string `json:"defaultStd" xml:"defaultStd,attr"`
+	VersionText string `json:"versionText" xml:"versionText"` // the first line printed by --version
+}
+
+// a source whose preprocessed output tells the family, the version and the default language standard of a compiler
+const probeSource = `#if defined(__clang__)
+cst-family clang
+cst-version __clang_major__ __clang_minor__ __clang_patchlevel__
+#elif defined(__INTEL_COMPILER)
+cst-family icc
+cst-version __INTEL_COMPILER __INTEL_COMPILER_UPDATE
+#elif defined(__GNUC__)
+cst-family gcc
+cst-version __GNUC__ __GNUC_MINOR__ __GNUC_PATCHLEVEL__
+#elif defined(_MSC_VER)
+cst-family msvc
+cst-version _MSC_FULL_VER
+#endif
+#if defined(_MSVC_LANG)
+cst-cplusplus _MSVC_LANG
+#elif defined(__cplusplus)
+cst-cplusplus __cplusplus
+#elif defined(__STDC_VERSION__)
+cst-stdc __STDC_VERSION__
+#endif
+#if defined(_M_X64)
+cst-target x86_64-pc-windows-msvc
+#elif defined(_M_ARM64)
+cst-target aarch64-pc-windows-msvc
+#elif defined(_M_IX86)
+cst-target i686-pc-windows-msvc
+#endif
+`
+
+// the -std values corresponding to the values of __cplusplus
+var cplusplusStandards = map[string]string{
+	"199711L": "c++98",
+	"201103L": "c++11",
+	"201402L": "c++14",
+	"201703L": "c++17",
+	"202002L": "c++20",
+	"202302L": "c++23",
+}
+
+// the -std values corresponding to the values of __STDC_VERSION__
+var cStandards = map[string]string{
+	"199409L": "c94",
+	"199901L": "c99",
+	"201112L": "c11",
+	"201710L": "c17",
+	"202311L": "c23",
+}
+
+// finds out the identity of the compiler of the given family. Whatever cannot be found out is left empty
+func probeCompiler(compiler, familyName string) CompilerIdentity {
+	identity := CompilerIdentity{Compiler: compiler, Family: familyName}
+
+	if out, err := exec.Command(compiler, "--version").CombinedOutput(); err == nil || len(out) > 0 {
+		identity.VersionText = strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	}
+
+	dir, err := os.MkdirTemp("", "cpp-stresstest-probe")
+	if err != nil {
+		return identity
+	}
+	defer os.RemoveAll(dir)
+
+	probeFile := filepath.Join(dir, "probe"+sourceExtension())
+	if err := os.WriteFile(probeFile, []byte(probeSource), 0644); err != nil {
+		return identity
+	}
+
+	preprocess := []string{"-E", "-P", probeFile}
+	if familyName == "msvc" {
+		preprocess = []string{"/nologo", "/EP", probeFile}
+	}
+	if out, err := exec.Command(compiler, preprocess...).Output(); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(out)))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || !strings.HasPrefix(fields[0], "cst-") {
+				continue
+			}
+			switch fields[0] {
+			case "cst-family":
+				identity.Family = fields[1]
+			case "cst-version":
+				identity.Version = strings.Join(fields[1:], ".")
+			case "cst-cplusplus":
+				if std, ok := cplusplusStandards[fields[1]]; ok {
+					identity.DefaultStd = std
+				} else {
+					identity.DefaultStd = fields[1]
+				}
+			case "cst-stdc":
+				if std, ok := cStandards[fields[1]]; ok {
+					identity.DefaultStd = std
+				} else {
+					identity.DefaultStd = fields[1]
+				}
+			case "cst-target":
+				identity.Target = fields[1]
+			}
+		}
+	}
+
+	if identity.Family != "msvc" {
+		if out, err := exec.Command(compiler, "-dumpmachine").Output(); err == nil {
+			identity.Target = strings.TrimSpace(string(out))
+		}
+	}
+	if identity.Family == "gcc" {
+		if out, err := exec.Command(compiler, "-dumpfullversion").Output(); err == nil {
+			identity.Version = strings.TrimSpace(string(out))
+		}
+	}
+
+	return identity
+}
+
+// probes the compilers of all the tools, returns the identities keyed by the name of the tool
+func probeCompilers() map[string]CompilerIdentity {
+	identities := make(map[string]CompilerIdentity)
+	probed := make(map[string]CompilerIdentity)
+	for _, tool := range tools() {
+		compiler, familyName := toolCompiler(tool)
+		if len(compiler) == 0 {
+			continue
+		}
+		identity, ok := probed[compiler]
+		if !ok {
+			identity = probeCompiler(compiler, familyName)
+			probed[compiler] = identity
+		}
+		identities[tool.Name] = identity
+	}
+	return identities
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// the maximum resident set size of the finished process, not available on windows
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
+
+// the name of the signal which terminated the process, there are no signals on windows
+func terminatingSignal(state *os.ProcessState) string {
+	return ""
+}
+
+// the process is killed when the command is cancelled, which is the default behaviour on windows
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+}
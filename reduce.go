@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// a frame of the backtrace printed by gcc on an internal compiler error, ie. "0x8d3c2f cxx_eval_call_expression(...)"
+	gccFrame = regexp.MustCompile(`^0x[0-9a-f]+ ([^\s(]+)`)
+	// a frame of the stack dump printed by clang when it crashes, ie. "#4 0x0000563d clang::Sema::CheckFoo(...) + 12"
+	clangFrame = regexp.MustCompile(`^\s*#\d+ 0x[0-9a-f]+ (?:\(.*\) )?([^\s(]+)`)
+	// the frames of the crash handlers, which are the same for all the crashes
+	crashHandlerFrame = regexp.MustCompile(`PrintStackTrace|SignalHandler|crash_signal|__restore_rt|raise|abort|diagnostic_|internal_error|fancy_abort`)
+	// the numbers in the messages, which change with the count
+	numbers = regexp.MustCompile(`[0-9]+`)
+	// a token with the white space after it, or white space at the beginning of the content
+	token = regexp.MustCompile(`\S+\s*|\s+`)
+)
+
+// the signature of a failure, which has to stay the same while the test is reduced
+func failureSignature(result TestResult, stderr string) string {
+	if result.Passed {
+		return ""
+	}
+	if result.Category == iceFailure {
+		for _, line := range strings.Split(stderr, "\n") {
+			m := gccFrame.FindStringSubmatch(line)
+			if m == nil {
+				m = clangFrame.FindStringSubmatch(line)
+			}
+			if m != nil && !crashHandlerFrame.MatchString(m[1]) {
+				return result.Category + ": " + m[1]
+			}
+		}
+	}
+	for _, d := range result.Diagnostics {
+		if d.Severity == "error" || d.Severity == "fatal" || d.Severity == "ice" {
+			if len(d.Code) > 0 {
+				return result.Category + ": " + d.Code
+			}
+			return result.Category + ": " + numbers.ReplaceAllString(d.Message, "N")
+		}
+	}
+	return result.Category
+}
+
+// runs the tool on the test and returns the signature of its failure, empty if it did not fail. The stderr of the
+// attempts is saved apart from the logs of the runs, so the one of the failure being reduced is kept
+func failureOf(tool ToolEntry, t generatedTest, mode, phase, std string, dir string) string {
+	result := runTool(tool, t, mode, phase, std, false, dir, filepath.Join("reduced", "logs"))
+	stderr := ""
+	if len(result.StderrFile) > 0 {
+		content, err := os.ReadFile(filepath.Join(dir, result.StderrFile))
+		check(err)
+		stderr = string(content)
+	}
+	return failureSignature(result, stderr)
+}
+
+// the delta debugging algorithm (ddmin), removes as many units as possible while the content made of the remaining
+// units keeps failing. The content is split into n chunks, and each chunk is tried alone before the content without
+// it; the chunks get finer as long as none of them can be removed
+func ddmin(units []string, fails func(string) bool) []string {
+	n := 2
+	for len(units) >= 2 {
+		chunk := (len(units) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(units) && !reduced; start += chunk {
+			subset := units[start:chunkEnd(units, start, chunk)]
+			if fails(strings.Join(subset, "")) {
+				units = append([]string{}, subset...)
+				n = 2
+				reduced = true
+			}
+		}
+		for start := 0; start < len(units) && !reduced; start += chunk {
+			complement := append(append([]string{}, units[:start]...), units[chunkEnd(units, start, chunk):]...)
+			if fails(strings.Join(complement, "")) {
+				units = complement
+				if n > 2 {
+					n--
+				}
+				reduced = true
+			}
+		}
+		if !reduced {
+			if n >= len(units) {
+				break
+			}
+			n *= 2
+			if n > len(units) {
+				n = len(units)
+			}
+		}
+	}
+	return units
+}
+
+// the end of the chunk of the units starting at start, the last chunk being shorter
+func chunkEnd(units []string, start, chunk int) int {
+	if start+chunk > len(units) {
+		return len(units)
+	}
+	return start + chunk
+}
+
+// splits the content into lines, keeping the line endings
+func lineUnits(content string) []string {
+	return strings.SplitAfter(content, "\n")
+}
+
+// splits the content into tokens separated by white space, keeping the white space after them
+func tokenUnits(content string) []string {
+	return token.FindAllString(content, -1)
+}
+
+// bisects the count of the test between 1 and the failing count, returns the smallest count failing with the same
+// signature, assuming that the failure persists above it
+func bisectCount(tool ToolEntry, entry *TestEntry, count int, signature string, mode, phase, std string, dir string) int {
+	lo, hi := 0, count
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		t := generateTest(entry, strconv.Itoa(mid))
+		s := failureOf(tool, t, mode, phase, std, dir)
+		fmt.Println("Bisecting:", t.name, "->", s)
+		if s == signature {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+// reduces a failing test: finds the smallest failing count, then removes everything from the generated sources which
+// is not needed for the failure
+func reduceCommand(args []string) {
+	flags, config := commandFlags("reduce")
+	testName := flags.String("test", "", "the name of the failing test")
+	count := flags.Int("count", 0, "the failing count of the test")
+	toolName := flags.String("tool", "", "the name of the tool the test fails with, by default the first one")
+	mode := flags.String("mode", defaultLimits, "the limits the test fails with, default or raised")
+	phase := flags.String("phase", linkPhase, "the phase the test fails in: preprocess, syntax, compile or link")
+	std := flags.String("std", "", "the standard the test fails with, by default the one of the flags")
+	flags.Parse(args)
+
+	loadTestSet(*config)
+	entry := findTest(*testName)
+	tool := findTool(*toolName)
+	if *count < 1 {
+		fmt.Println("the failing count has to be given with -count")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dir := testSetDir()
+	check(os.MkdirAll(dir, os.ModePerm))
+
+	t := generateTest(entry, strconv.Itoa(*count))
+	if _, familyName := toolCompiler(tool); len(familyName) == 0 {
+		*phase = ""
+	}
+	signature := failureOf(tool, t, *mode, *phase, *std, dir)
+	if len(signature) == 0 {
+		fmt.Println(t.name, "does not fail with", tool.Name)
+		return
+	}
+	fmt.Println("Failure:", signature)
+
+	minimal := bisectCount(tool, entry, *count, signature, *mode, *phase, *std, dir)
+	fmt.Println("Smallest failing count:", minimal)
+
+	t = generateTest(entry, strconv.Itoa(minimal))
+	candidate := reductionCandidate(t)
+	originals, files := t.sources(""), candidate.sources("")
+	for i := range files {
+		content, err := os.ReadFile(filepath.Join(dir, originals[i]))
+		check(err)
+		check(os.WriteFile(filepath.Join(dir, files[i]), content, 0644))
+	}
+
+	// the files of a test made of several translation units are reduced one after the other, the others keeping the
+	// content they are reduced to
+	check(os.MkdirAll(filepath.Join(dir, "reduced"), os.ModePerm))
+	attempts := 0
+	for i, file := range files {
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		check(err)
+		fails := func(c string) bool {
+			attempts++
+			check(os.WriteFile(filepath.Join(dir, file), []byte(c), 0644))
+			return failureOf(tool, candidate, *mode, *phase, *std, dir) == signature
+		}
+
+		// ddmin keeps at least one unit, yet a translation unit may not be needed at all
+		reduced := ""
+		if !fails(reduced) {
+			units := ddmin(lineUnits(string(content)), fails)
+			fmt.Println("Lines of", originals[i]+":", len(lineUnits(string(content))), "->", len(units))
+			units = ddmin(tokenUnits(strings.Join(units, "")), fails)
+			reduced = strings.Join(units, "")
+		}
+		check(os.WriteFile(filepath.Join(dir, file), []byte(reduced), 0644))
+
+		reducedName := filepath.Join(dir, "reduced", originals[i])
+		check(os.WriteFile(reducedName, []byte(reduced), 0644))
+		fmt.Printf("Reduced %s from %d to %d bytes: %s\n", originals[i], len(content), len(reduced), reducedName)
+	}
+	for _, file := range files {
+		os.Remove(filepath.Join(dir, file))
+	}
+
+	fmt.Printf("Reduced %s in %d attempts\n", t.name, attempts)
+}
+
+// the test with copies of its source files, which the reduction works on. The copies are written beside the
+// originals, so the relative includes keep working
+func reductionCandidate(t generatedTest) generatedTest {
+	candidate := t
+	candidate.name = t.name + "-reduced"
+	candidate.fileName = reducedFileName(t.fileName)
+	candidate.units = make([]translationUnit, len(t.units))
+	for i, u := range t.units {
+		u.fileName = reducedFileName(u.fileName)
+		candidate.units[i] = u
+	}
+	return candidate
+}
+
+// the name of the copy of a source file the reduction works on
+func reducedFileName(fileName string) string {
+	ext := filepath.Ext(fileName)
+	return strings.TrimSuffix(fileName, ext) + "-reduced" + ext
+}
+
+// the test with the given name from the test set
+func findTest(name string) *TestEntry {
+	for i := range testSet.Tests {
+		if testSet.Tests[i].TestName == name {
+			return &testSet.Tests[i]
+		}
+	}
+	if _, ok := funcMap[name]; ok {
+		return &TestEntry{TestName: name}
+	}
+	fmt.Println("unknown test:", name)
+	os.Exit(2)
+	return nil
+}
+
+// the tool with the given name, or the first one if the name is empty
+func findTool(name string) ToolEntry {
+	for _, tool := range tools() {
+		if len(name) == 0 || tool.Name == name {
+			return tool
+		}
+	}
+	fmt.Println("unknown tool:", name)
+	os.Exit(2)
+	return ToolEntry{}
+}
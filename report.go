@@ -0,0 +1,275 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the data the report is generated from
+type reportData struct {
+	Run       RunResults
+	Generated string
+	Modes     []modeResults
+	Phases    []phaseSummary
+	Timings   []TestResult // the results with timings measured by the compiler
+	MaxTiming float64      // the longest of the timings, the width of the bars
+	Binaries  []TestResult // the results with an analyzed object file or executable
+	DebugInfo []TestResult // the results with analyzed debug information
+	Standards []standardLimits
+	StdNames  []string // the standards of the sweep, the columns of Standards
+}
+
+// the results of the run in one of the limit modes and phases, reported separately
+type modeResults struct {
+	Mode      string
+	Phase     string
+	DebugInfo bool
+	Std       string
+	Results   []TestResult
+}
+
+// the limits of a test compiled with each standard of the sweep
+type standardLimits struct {
+	Tool, Test, Mode, Phase string
+	Limits                  []standardLimit // in the order of the sweep
+}
+
+// the largest count of a test which passed with a standard and the smallest which failed, or why it was not run
+type standardLimit struct {
+	LargestPassed, SmallestFailed, NotApplicable string
+}
+
+// the phase a test failed in first, when it was run in several phases
+type phaseSummary struct {
+	Tool, Test, Count, Mode string
+	Phases                  []TestResult // in the order of the phases
+	FirstFailed             string
+}
+
+// the html report of a run, the header lists the compilers which produced the results
+const reportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Run.SetName}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+.failed { background: #fdd; }
+.superlinear { background: #ffd; }
+.skipped { color: #888; }
+.warning { color: #a60; }
+.bar { display: flex; width: 400px; height: 1em; }
+.bar span { height: 100%; }
+.preprocessing { background: #8dd3c7; }
+.parsing { background: #80b1d3; }
+.templates { background: #fb8072; }
+.constexpr { background: #fdb462; }
+.optimization { background: #b3de69; }
+.codegen { background: #bc80bd; }
+</style>
+</head>
+<body>
+<h1>{{.Run.SetName}}</h1>
+<p>Generated at {{.Generated}}</p>
+
+<h2>Compilers</h2>
+<table>
+<tr><th>Compiler</th><th>Family</th><th>Version</th><th>Target</th><th>Default standard</th><th>--version</th></tr>
+{{range .Run.Compilers}}<tr><td>{{.Compiler}}</td><td>{{.Family}}</td><td>{{.Version}}</td><td>{{.Target}}</td><td>{{.DefaultStd}}</td><td>{{.VersionText}}</td></tr>
+{{end}}</table>
+
+{{with .Phases}}<h2>Phases</h2>
+<p>The tests run in several phases, with the phase the compiler failed in first.</p>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Count</th><th>Mode</th><th>Phases</th><th>First failed</th></tr>
+{{range .}}<tr{{if .FirstFailed}} class="failed"{{end}}><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Count}}</td><td>{{.Mode}}</td><td>{{range .Phases}}{{.Phase}}: {{if .Skipped}}skipped{{else if .Passed}}passed{{else}}{{.Category}}{{end}} {{end}}</td><td>{{.FirstFailed}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Standards}}<h2>Standards</h2>
+<p>The limits of the tests compiled with each standard: the largest count which passed and the smallest one which failed.</p>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Mode</th><th>Phase</th>{{range $.StdNames}}<th>{{.}}</th>{{end}}</tr>
+{{range .}}<tr><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Mode}}</td><td>{{.Phase}}</td>{{range .Limits}}{{if .NotApplicable}}<td class="skipped" title="{{.NotApplicable}}">n/a</td>{{else}}<td{{if .SmallestFailed}} class="failed"{{end}}>{{with .LargestPassed}}passed {{.}}{{end}}{{with .SmallestFailed}} failed {{.}}{{end}}</td>{{end}}{{end}}</tr>
+{{end}}</table>
+{{end}}
+{{range .Modes}}<h2>Results with {{.Mode}} limits{{with .Phase}}, {{.}} phase{{end}}{{if .DebugInfo}}, with debug information{{end}}{{with .Std}}, {{.}}{{end}}</h2>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Count</th><th>Exit code</th><th>Passed</th><th>Time (s)</th><th>Memory (KB)</th><th>Predicted (s, KB)</th><th>Failure</th><th>Diagnostic</th></tr>
+{{range .Results}}{{if .Skipped}}<tr class="skipped"><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Count}}</td><td></td><td>skipped</td><td></td><td></td><td>{{printf "%.3f" .PredictedTime}}, {{printf "%.0f" .PredictedMemory}}</td><td></td><td>{{.Skipped}}</td></tr>
+{{else}}<tr{{if not .Passed}} class="failed"{{end}}><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Count}}</td><td>{{.ExitCode}}</td><td>{{.Passed}}</td><td>{{printf "%.3f" .WallTime}}</td><td>{{.MaxRSS}}</td><td>{{if .PredictedTime}}{{printf "%.3f" .PredictedTime}}, {{printf "%.0f" .PredictedMemory}}{{end}}</td><td>{{.Category}}</td><td>{{firstError .Diagnostics}}{{with .StderrFile}} (<a href="{{.}}">stderr</a>){{end}}{{with .Reproducer}} (<a href="{{.}}">reproducer</a>){{end}}{{with .Warning}} <span class="warning">warning: {{.}}</span>{{end}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}
+{{with .Timings}}<h2>Compile time</h2>
+<p>Where the compilers spent their time, as measured by themselves:
+<span class="preprocessing">&nbsp;preprocessing&nbsp;</span> <span class="parsing">&nbsp;parsing&nbsp;</span>
+<span class="templates">&nbsp;templates&nbsp;</span> <span class="constexpr">&nbsp;constexpr&nbsp;</span>
+<span class="optimization">&nbsp;optimization&nbsp;</span> <span class="codegen">&nbsp;codegen&nbsp;</span></p>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Count</th><th>Mode</th><th>Phase</th><th>Time (s)</th><th></th></tr>
+{{range .}}<tr><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Count}}</td><td>{{.Mode}}</td><td>{{.Phase}}</td><td>{{printf "%.3f" .Timings.Total}}</td><td><div class="bar">{{range .Timings.Parts}}<span class="{{.Name}}" style="width: {{percent .Seconds $.MaxTiming}}%" title="{{.Name}} {{printf "%.3f" .Seconds}}s"></span>{{end}}</div></td></tr>
+{{end}}</table>
+{{end}}
+{{with .Binaries}}<h2>Binaries</h2>
+<p>The object files and the executables, with the sizes of their sections (as counted by <code>size</code>) and their symbols.</p>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Count</th><th>Mode</th><th>File</th><th>Text</th><th>Data</th><th>BSS</th><th>Symbols</th><th>Extern</th><th>Longest symbol</th><th>Vtables</th><th>Relocations</th><th>Symbol check</th></tr>
+{{range .}}<tr{{if .Binary.SymbolCheck}} class="failed"{{end}}><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Count}}</td><td>{{.Mode}}</td><td>{{.Binary.File}}</td><td>{{.Binary.Text}}</td><td>{{.Binary.Data}}</td><td>{{.Binary.BSS}}</td><td>{{.Binary.Symbols}}</td><td>{{.Binary.ExternSymbols}}</td><td title="{{.Binary.LongestSymbolName}}">{{.Binary.LongestSymbol}}</td><td>{{.Binary.VtableSize}}</td><td>{{.Binary.Relocations}}</td><td>{{.Binary.SymbolCheck}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .DebugInfo}}<h2>Debug information</h2>
+<p>The DWARF debug information of the tests compiled with it, with the deepest nesting of the namespaces and of the classes, and the longest chain of the base classes of the generated hierarchies.</p>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Count</th><th>Mode</th><th>Phase</th><th>DIEs</th><th>Debug sections</th><th>Depth</th><th>Namespaces</th><th>Classes</th><th>Base classes</th><th>Depth check</th></tr>
+{{range .}}<tr{{if .DWARF.DepthCheck}} class="failed"{{end}}><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Count}}</td><td>{{.Mode}}</td><td>{{.Phase}}</td><td>{{.DWARF.DIEs}}</td><td>{{.DWARF.DebugSize}}{{range .DWARF.Sections}}<br>{{.Name}} {{.Size}}{{end}}</td><td>{{.DWARF.MaxDepth}}</td><td>{{.DWARF.NamespaceDepth}}</td><td>{{.DWARF.ClassDepth}}</td><td>{{.DWARF.BaseDepth}}</td><td>{{.DWARF.DepthCheck}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Run.Scaling}}<h2>Scaling</h2>
+<p>The time and memory of the successful results fitted against the count, growths worse than linear are highlighted.</p>
+<table>
+<tr><th>Tool</th><th>Test</th><th>Mode</th><th>Phase</th><th>Metric</th><th>Best fit</th><th>a</th><th>b</th><th>R&sup2;</th><th>Counts</th></tr>
+{{range .}}<tr{{if .Superlinear}} class="superlinear"{{end}}><td>{{.Tool}}</td><td>{{.Test}}</td><td>{{.Mode}}</td><td>{{.Phase}}</td><td>{{.Metric}}</td><td>{{.Model}}</td><td>{{printf "%.4g" .A}}</td><td>{{printf "%.4g" .B}}</td><td>{{printf "%.3f" .R2}}</td><td>{{.Points}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`
+
+// writes the html report of the run into dir
+func writeReport(run RunResults, dir string) {
+	t := template.Must(template.New("report").Funcs(template.FuncMap{"firstError": firstError, "percent": percent}).Parse(reportTemplate))
+
+	f, err := os.Create(filepath.Join(dir, "report.html"))
+	check(err)
+	defer f.Close()
+
+	data := reportData{Run: run, Generated: time.Now().Format(time.RFC3339)}
+	for _, mode := range limitModes() {
+		for _, phase := range append(phaseOrder, "") {
+			for _, debugInfo := range []bool{false, true} {
+				for _, std := range standards() {
+					m := modeResults{Mode: mode, Phase: phase, DebugInfo: debugInfo, Std: std}
+					for _, r := range run.Results {
+						if r.Mode == mode && r.Phase == phase && r.Debug == debugInfo && r.Std == std {
+							m.Results = append(m.Results, r)
+						}
+					}
+					if len(m.Results) > 0 {
+						data.Modes = append(data.Modes, m)
+					}
+				}
+			}
+		}
+	}
+	data.Phases = summarizePhases(run.Results)
+	if len(testSet.Standards) > 0 {
+		data.Standards, data.StdNames = summarizeStandards(run.Results), testSet.Standards
+	}
+	for _, r := range run.Results {
+		if r.DWARF != nil {
+			data.DebugInfo = append(data.DebugInfo, r)
+		}
+		if r.Binary != nil {
+			data.Binaries = append(data.Binaries, r)
+		}
+		if r.Timings != nil {
+			data.Timings = append(data.Timings, r)
+			if r.Timings.Total() > data.MaxTiming {
+				data.MaxTiming = r.Timings.Total()
+			}
+		}
+	}
+
+	check(t.Execute(f, data))
+}
+
+// the results of the tests run in several phases, grouped by tool, test, count and mode
+func summarizePhases(results []TestResult) []phaseSummary {
+	type key struct{ tool, test, count, mode string }
+	summaries := make(map[key]*phaseSummary)
+	keys := make([]key, 0)
+	for _, r := range results {
+		if len(r.Phase) == 0 {
+			continue
+		}
+		k := key{r.Tool, r.Test, r.Count, r.Mode}
+		s, ok := summaries[k]
+		if !ok {
+			s = &phaseSummary{Tool: r.Tool, Test: r.Test, Count: r.Count, Mode: r.Mode}
+			summaries[k] = s
+			keys = append(keys, k)
+		}
+		s.Phases = append(s.Phases, r)
+	}
+
+	result := make([]phaseSummary, 0)
+	for _, k := range keys {
+		s := summaries[k]
+		if len(s.Phases) < 2 {
+			continue
+		}
+		sort.SliceStable(s.Phases, func(i, j int) bool { return phaseIndex(s.Phases[i].Phase) < phaseIndex(s.Phases[j].Phase) })
+		for _, r := range s.Phases {
+			if !r.Passed && len(r.Skipped) == 0 {
+				s.FirstFailed = r.Phase
+				break
+			}
+		}
+		result = append(result, *s)
+	}
+	return result
+}
+
+// the limits of the tests for each standard of the sweep, grouped by tool, test, mode and phase
+func summarizeStandards(results []TestResult) []standardLimits {
+	type key struct{ tool, test, mode, phase string }
+	summaries := make(map[key]*standardLimits)
+	keys := make([]key, 0)
+	for _, r := range results {
+		k := key{r.Tool, r.Test, r.Mode, r.Phase + debugName(r.Debug)}
+		s, ok := summaries[k]
+		if !ok {
+			s = &standardLimits{Tool: r.Tool, Test: r.Test, Mode: r.Mode, Phase: k.phase,
+				Limits: make([]standardLimit, len(testSet.Standards))}
+			summaries[k] = s
+			keys = append(keys, k)
+		}
+		for i, std := range testSet.Standards {
+			if r.Std != std {
+				continue
+			}
+			l := &s.Limits[i]
+			switch {
+			case len(r.Skipped) > 0:
+				if strings.HasPrefix(r.Skipped, "not applicable") {
+					l.NotApplicable = r.Skipped
+				}
+			case r.Passed:
+				if countGreater(r.Count, l.LargestPassed) {
+					l.LargestPassed = r.Count
+				}
+			case len(l.SmallestFailed) == 0 || countGreater(l.SmallestFailed, r.Count):
+				l.SmallestFailed = r.Count
+			}
+		}
+	}
+
+	result := make([]standardLimits, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, *summaries[k])
+	}
+	return result
+}
+
+// the part as a percentage of the whole
+func percent(part, whole float64) string {
+	if whole <= 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(100*part/whole, 'f', 2, 64)
+}
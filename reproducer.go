@@ -0,0 +1,169 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the environment variables which can influence the behaviour of a compiler
+var compilerEnvironment = []string{"PATH", "CPATH", "C_INCLUDE_PATH", "CPLUS_INCLUDE_PATH", "LIBRARY_PATH",
+	"LD_LIBRARY_PATH", "COMPILER_PATH", "GCC_EXEC_PREFIX", "INCLUDE", "LIB", "LIBPATH", "CL", "_CL_", "LANG",
+	"LC_ALL", "TMPDIR", "TMP", "TEMP"}
+
+// the command preprocessing a source of the test with the compiler of the tool, with the same flags the test was
+// compiled with
+func preprocessArgs(tool ToolEntry, t generatedTest, source, mode, std string) []string {
+	compiler, familyName := toolCompiler(tool)
+	args := []string{compiler}
+	args = append(args, splitCommandLine(toolFlags(familyName, t, mode, "", std, false))...)
+	args = append(args, familyOf(familyName).includeFlag+"inc")
+	if familyName == "msvc" {
+		return append(args, "/E", source)
+	}
+	return append(args, "-E", source)
+}
+
+// the values of the environment variables relevant for compilers, in NAME=value format
+func relevantEnvironment() []string {
+	env := make([]string, 0)
+	for _, name := range compilerEnvironment {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// creates a self contained bundle reproducing the crash of the tool on the test, ready to be attached to a bug
+// report. Returns the name of the bundle relative to dir
+func createReproducer(tool ToolEntry, t generatedTest, result TestResult, dir string) string {
+	files := make(map[string][]byte)
+
+	for _, fileName := range t.sources("") {
+		source, err := os.ReadFile(filepath.Join(dir, fileName))
+		check(err)
+		files[fileName] = source
+	}
+	for _, fileName := range includedFiles(t.sources(""), dir) {
+		header, err := os.ReadFile(filepath.Join(dir, fileName))
+		check(err)
+		files[fileName] = header
+	}
+
+	what := "compiling " + t.fileName
+	if len(t.units) > 0 {
+		what = "building " + t.name + " from " + strconv.Itoa(len(t.units)+1) + " translation units"
+	}
+	readme := "The command below crashed the tool " + tool.Name + " while " + what + ".\n" +
+		"The headers of the test are in inc, and the preprocessed sources (if the tool is a compiler) reproduce the\n" +
+		"crash without them.\n\n" +
+		"command:\n\t" + result.Command + "\n"
+
+	// every unit is preprocessed, the crash may be in any of them
+	if compiler, _ := toolCompiler(tool); len(compiler) > 0 {
+		for _, source := range t.sources("") {
+			args := preprocessArgs(tool, t, source, result.Mode, result.Std)
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Dir = dir
+			if preprocessed, err := cmd.Output(); err == nil {
+				name := strings.TrimSuffix(source, filepath.Ext(source)) + preprocessedExtension()
+				files[name] = preprocessed
+				readme += "\npreprocessed with:\n\t" + strings.Join(args, " ") + "\n"
+			} else {
+				readme += "\npreprocessing failed with:\n\t" + strings.Join(args, " ") + "\n\t" + err.Error() + "\n"
+			}
+		}
+	}
+
+	files["README.txt"] = []byte(readme)
+	files["command.txt"] = []byte(result.Command + "\n")
+
+	if len(result.StderrFile) > 0 {
+		stderr, err := os.ReadFile(filepath.Join(dir, result.StderrFile))
+		check(err)
+		files["stderr.txt"] = stderr
+	}
+
+	if result.Compiler != nil {
+		identity, err := json.MarshalIndent(result.Compiler, "", "  ")
+		check(err)
+		files["compiler.json"] = identity
+	}
+
+	environment := "os=" + runtime.GOOS + "\narch=" + runtime.GOARCH + "\n" + strings.Join(relevantEnvironment(), "\n") + "\n"
+	files["environment.txt"] = []byte(environment)
+
+	name := filepath.Join("reproducers", result.fileName()+".tar.gz")
+	check(os.MkdirAll(filepath.Join(dir, "reproducers"), os.ModePerm))
+	writeTarGz(filepath.Join(dir, name), strings.TrimSuffix(filepath.Base(name), ".tar.gz"), files)
+	return name
+}
+
+// the #include "..." directives of a source
+var quotedInclude = regexp.MustCompile(`(?m)^\s*#\s*include\s*"([^"]+)"`)
+
+// the headers included by the sources in dir, directly or through other headers, relative to dir. A header is looked
+// up beside the file including it, then in the include directory, the way the compilers are told to
+func includedFiles(sources []string, dir string) []string {
+	seen := make(map[string]bool)
+	pending := append([]string{}, sources...)
+	headers := make([]string, 0)
+	for len(pending) > 0 {
+		fileName := pending[0]
+		pending = pending[1:]
+		content, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			continue
+		}
+		for _, match := range quotedInclude.FindAllStringSubmatch(string(content), -1) {
+			for _, candidate := range []string{filepath.Join(filepath.Dir(fileName), match[1]), filepath.Join("inc", match[1])} {
+				if _, err := os.Stat(filepath.Join(dir, candidate)); err == nil {
+					candidate = filepath.ToSlash(candidate)
+					if !seen[candidate] {
+						seen[candidate] = true
+						headers = append(headers, candidate)
+						pending = append(pending, candidate)
+					}
+					break
+				}
+			}
+		}
+	}
+	return headers
+}
+
+// writes the files into a gzipped tar archive, all of them being placed in the given directory of the archive
+func writeTarGz(fileName, directory string, files map[string][]byte) {
+	f, err := os.Create(fileName)
+	check(err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		content := files[name]
+		check(tw.WriteHeader(&tar.Header{Name: directory + "/" + name, Mode: 0644, Size: int64(len(content)), ModTime: now}))
+		_, err := tw.Write(content)
+		check(err)
+	}
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the result of running one tool on one generated test
+type TestResult struct {
+	XMLName  xml.Name `json:"-" xml:"result"`
+	Tool     string   `json:"tool" xml:"tool,attr"`
+	Test     string   `json:"test" xml:"test,attr"`
+	Count    string   `json:"count" xml:"count,attr"`
+	Mode     string   `json:"mode" xml:"mode,attr"`                               // the limits of the compiler, default or raised
+	Phase    string   `json:"phase,omitempty" xml:"phase,attr,omitempty"`         // the phase the compiler stopped after
+	Debug    bool     `json:"debugInfo,omitempty" xml:"debugInfo,attr,omitempty"` // compiled with debug information
+	Std      string   `json:"std,omitempty" xml:"std,attr,omitempty"`             // the standard of the sweep
+	Command  string   `json:"command" xml:"command"`
+	ExitCode int      `json:"exitCode" xml:"exitCode"`
+	Passed   bool     `json:"passed" xml:"passed"`
+	WallTime float64  `json:"wallTime" xml:"wallTime"` // average of all the invocations, in seconds
+	MaxRSS   int64    `json:"maxRSS" xml:"maxRSS"`     // maximum of all the invocations, in kilobytes
+
+	Compiler *CompilerIdentity `json:"compiler,omitempty" xml:"compiler,omitempty"` // nil if the tool is not a compiler
+
+	Signal      string       `json:"signal,omitempty" xml:"signal,omitempty"`     // the signal the tool was killed with
+	Category    string       `json:"category,omitempty" xml:"category,omitempty"` // the category of the failure
+	StderrFile  string       `json:"stderrFile,omitempty" xml:"stderrFile,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty" xml:"diagnostic"`
+	Reproducer  string       `json:"reproducer,omitempty" xml:"reproducer,omitempty"` // the bundle created for an ICE
+
+	PredictedTime   float64 `json:"predictedTime,omitempty" xml:"predictedTime,omitempty"`     // extrapolated from the history
+	PredictedMemory float64 `json:"predictedMemory,omitempty" xml:"predictedMemory,omitempty"` // extrapolated from the history
+	Skipped         string  `json:"skipped,omitempty" xml:"skipped,omitempty"`                 // why the test was not run
+	Warning         string  `json:"warning,omitempty" xml:"warning,omitempty"`                 // the predicted overrun of a test run anyway
+
+	Timings *CompileTimings `json:"timings,omitempty" xml:"timings,omitempty"` // measured by the compiler
+	Binary  *BinaryStats    `json:"binary,omitempty" xml:"binary,omitempty"`   // the object file or executable
+	DWARF   *DebugStats     `json:"dwarf,omitempty" xml:"dwarf,omitempty"`     // its debug information
+}
+
+// the header of the CSV results file, in the order of the fields written by csvRecord
+var csvHeader = []string{"tool", "test", "count", "mode", "phase", "debugInfo", "std", "exitCode", "passed", "wallTime", "maxRSS", "command",
+	"category", "signal", "error", "compilerFamily", "compilerVersion", "target", "defaultStd", "predictedTime",
+	"predictedMemory", "skipped", "warning", "preprocessing", "parsing", "templates", "constexpr", "optimization", "codegen",
+	"text", "data", "bss", "symbols", "externSymbols", "longestSymbol", "vtableSize", "relocations", "symbolCheck",
+	"dies", "debugSize", "namespaceDepth", "classDepth", "depthCheck"}
+
+func (r *TestResult) csvRecord() []string {
+	record := []string{r.Tool, r.Test, r.Count, r.Mode, r.Phase, strconv.FormatBool(r.Debug), r.Std, strconv.Itoa(r.ExitCode), strconv.FormatBool(r.Passed),
+		strconv.FormatFloat(r.WallTime, 'f', 3, 64), strconv.FormatInt(r.MaxRSS, 10), r.Command,
+		r.Category, r.Signal, firstError(r.Diagnostics)}
+	if r.Compiler != nil {
+		record = append(record, r.Compiler.Family, r.Compiler.Version, r.Compiler.Target, r.Compiler.DefaultStd)
+	} else {
+		record = append(record, "", "", "", "")
+	}
+	record = append(record, strconv.FormatFloat(r.PredictedTime, 'f', 3, 64),
+		strconv.FormatFloat(r.PredictedMemory, 'f', 0, 64), r.Skipped, r.Warning)
+	if r.Timings != nil {
+		for _, p := range r.Timings.Parts() {
+			record = append(record, strconv.FormatFloat(p.Seconds, 'f', 3, 64))
+		}
+	} else {
+		record = append(record, "", "", "", "", "", "")
+	}
+	if b := r.Binary; b != nil {
+		record = append(record, strconv.FormatUint(b.Text, 10), strconv.FormatUint(b.Data, 10), strconv.FormatUint(b.BSS, 10),
+			strconv.Itoa(b.Symbols), strconv.Itoa(b.ExternSymbols), strconv.Itoa(b.LongestSymbol),
+			strconv.FormatUint(b.VtableSize, 10), strconv.Itoa(b.Relocations), b.SymbolCheck)
+	} else {
+		record = append(record, "", "", "", "", "", "", "", "", "")
+	}
+	if d := r.DWARF; d != nil {
+		return append(record, strconv.Itoa(d.DIEs), strconv.FormatUint(d.DebugSize, 10), strconv.Itoa(d.NamespaceDepth),
+			strconv.Itoa(d.ClassDepth), d.DepthCheck)
+	}
+	return append(record, "", "", "", "", "")
+}
+
+// all the results of a run, with the identities of the compilers which produced them
+type RunResults struct {
+	XMLName   xml.Name           `json:"-" xml:"results"`
+	SetName   string             `json:"setName" xml:"setName,attr"`
+	Compilers []CompilerIdentity `json:"compilers" xml:"compiler"`
+	Results   []TestResult       `json:"results" xml:"result"`
+	Scaling   []ScalingFit       `json:"scaling,omitempty" xml:"scaling"`
+}
+
+// the tools the tests are run with, by default only the compiler of the test set
+func tools() []ToolEntry {
+	if len(testSet.Tools) > 0 {
+		return testSet.Tools
+	}
+	return []ToolEntry{compilerTool(compilerName(), familyName())}
+}
+
+// checks the tools of the test set before anything is run, so a mistake in the json file does not stop a run after
+// hours. Compiles their regular expressions
+func validateTools() error {
+	for i := range testSet.Tools {
+		tool := &testSet.Tools[i]
+		if len(splitCommandLine(tool.Command)) == 0 {
+			return fmt.Errorf("tool %q has no command", tool.Name)
+		}
+		if _, ok := compilerFamilies[tool.CompilerFamily]; len(tool.CompilerFamily) > 0 && !ok {
+			return fmt.Errorf("tool %q has an unknown compiler family: %s", tool.Name, tool.CompilerFamily)
+		}
+		if len(tool.StdoutRegex) > 0 {
+			regex, err := regexp.Compile(tool.StdoutRegex)
+			if err != nil {
+				return fmt.Errorf("tool %q has an invalid stdoutRegex: %v", tool.Name, err)
+			}
+			tool.stdoutRegex = regex
+		}
+	}
+	return nil
+}
+
+// a tool compiling the tests with the given compiler, naming the output with the flag of the phase
+func compilerTool(compiler, familyName string) ToolEntry {
+	f := familyOf(familyName)
+	output := "{outputFlag}{output}"
+	if strings.HasSuffix(f.outputFlag, " ") {
+		output = "{outputFlag} {output}"
+	}
+	return ToolEntry{
+		Name:           compiler,
+		Command:        "{compiler} {flags} " + f.includeFlag + "{includeDir} " + output + " {source}",
+		Compiler:       compiler,
+		CompilerFamily: familyName,
+	}
+}
+
+// expands the command template of the tool for the given test into the arguments of the process to start. A word
+// consisting of only one placeholder is replaced by all the words of the value, so {flags} can expand to many flags.
+// With raised limits the flags raising the limit stressed by the test are appended to {flags}, as well as the flag
+// selecting the standard, the one stopping the compiler after the phase and the one generating the debug information
+func expandCommand(tool ToolEntry, t generatedTest, mode, phase, std string, debugInfo bool) []string {
+	_, familyName := toolCompiler(tool)
+	return expandCommandFor(tool, t, mode, phase, std, debugInfo, []string{t.fileName}, phaseOutput(familyName, t, phase))
+}
+
+// expands the command template of the tool for the given sources of the test, written into output
+func expandCommandFor(tool ToolEntry, t generatedTest, mode, phase, std string, debugInfo bool, sources []string, output string) []string {
+	compiler, familyName := toolCompiler(tool)
+	outputFlag := ""
+	if len(familyName) > 0 {
+		outputFlag = strings.TrimSpace(outputFlagOf(familyName, phase))
+	}
+	values := map[string]string{
+		"{compiler}":   compiler,
+		"{outputFlag}": outputFlag,
+		"{flags}":      toolFlags(familyName, t, mode, phase, std, debugInfo),
+		"{source}":     strings.Join(sources, " "),
+		"{output}":     output,
+		"{includeDir}": "inc",
+		"{count}":      t.count,
+		"{test}":       t.entry.TestName,
+	}
+
+	args := make([]string, 0)
+	for _, word := range splitCommandLine(tool.Command) {
+		if v, ok := values[word]; ok {
+			args = append(args, splitCommandLine(v)...)
+			continue
+		}
+		for k, v := range values {
+			word = strings.ReplaceAll(word, k, v)
+		}
+		args = append(args, word)
+	}
+	return args
+}
+
+// the flags the test is compiled with by a compiler of the family in the given mode, up to the given phase, in the
+// given standard (or the one of the flags if it is empty), with or without debug information
+func toolFlags(familyName string, t generatedTest, mode, phase, std string, debugInfo bool) string {
+	flags := familyFlagsOf(familyName) + " " + t.entry.CompilerFlags + " " + moduleFlags(familyName, t) + " " + standardFlag(familyName, std)
+	if mode == raisedLimits {
+		flags += " " + raisingFlags(familyName, t.entry.TestName, t.count)
+	}
+	flags += " " + phaseFlag(familyName, phase)
+	if debugInfo && len(familyName) > 0 {
+		flags += " " + familyOf(familyName).debugFlag
+	}
+	return strings.TrimSpace(flags)
+}
+
+// splits a command line into words, taking care of single and double quotes
+func splitCommandLine(command string) []string {
+	words := make([]string, 0)
+	current := ""
+	inWord := false
+	var quote rune
+	for _, c := range command {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current += string(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, current)
+				current = ""
+				inWord = false
+			}
+		default:
+			current += string(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, current)
+	}
+	return words
+}
+
+// runs the tool on the test in dir as many times as requested by compilationTimes, saving its stderr into the logs
+// directory of dir. A test built by several steps stops at the first failing one, its time being the sum of the times
+// of the steps
+func runTool(tool ToolEntry, t generatedTest, mode, phase, std string, debugInfo bool, dir, logs string) TestResult {
+	steps := buildSteps(tool, t, mode, phase, std, debugInfo)
+	result := TestResult{Tool: tool.Name, Test: t.entry.TestName, Count: t.count, Mode: mode, Phase: phase, Debug: debugInfo,
+		Std: std, Command: commandLine(steps), Passed: true}
+
+	// the compilers measuring their phases print a table into stderr, or write a trace
+	_, familyName := toolCompiler(tool)
+	timeReport := false
+	traceFile := filepath.Join(dir, traceFileName(result))
+	if testSet.TimeReport {
+		if flags := timeReportFlags(familyName, traceFileName(result)); len(flags) > 0 {
+			check(os.MkdirAll(filepath.Join(dir, "traces"), os.ModePerm))
+			for i := range steps {
+				if steps[i].compiles {
+					steps[i].args = append(steps[i].args, flags...)
+				}
+			}
+			result.Command = commandLine(steps)
+			timeReport = true
+		}
+	}
+
+	times := testSet.CompilationTimes
+	if times < 1 {
+		times = 1
+	}
+
+	var totalTime time.Duration
+	var stderr bytes.Buffer
+	outputMatched := true
+	timedOut := false
+	for i := 0; i < times; i++ {
+		var stdout bytes.Buffer
+		stderr.Reset()
+		result.Timings = nil
+
+		// the timeout is for the whole build, not for each of its steps
+		ctx, cancel := buildContext()
+		for s, step := range steps {
+			if ctx.Err() != nil {
+				timedOut = true
+				result.ExitCode = -1
+				break
+			}
+			stdout.Reset()
+			var stepStderr bytes.Buffer
+			if timeReport && step.compiles {
+				os.Remove(traceFile)
+			}
+			state, elapsed, stepTimedOut, err := runStep(ctx, step.args, dir, &stdout, &stepStderr)
+			totalTime += elapsed
+			timedOut = timedOut || stepTimedOut
+
+			if state != nil {
+				result.ExitCode = state.ExitCode()
+				result.Signal = terminatingSignal(state)
+				if rss := maxRSS(state); rss > result.MaxRSS {
+					result.MaxRSS = rss
+				}
+			} else {
+				fmt.Println("error:", err)
+				stepStderr.WriteString(err.Error() + "\n")
+				result.ExitCode = -1
+			}
+			stderr.Write(stepStderr.Bytes())
+
+			// the timings of the compilations of the translation units add up
+			if timeReport && step.compiles {
+				result.Timings = result.Timings.add(parseTimings(familyName, stepStderr.String(), traceFile))
+			}
+			if result.ExitCode != 0 && s < len(steps)-1 {
+				break
+			}
+		}
+		cancel()
+
+		if tool.stdoutRegex != nil && !tool.stdoutRegex.Match(stdout.Bytes()) {
+			outputMatched = false
+		}
+		if result.ExitCode != tool.ExitCode || !outputMatched {
+			result.Passed = false
+		}
+	}
+
+	result.WallTime = totalTime.Seconds() / float64(times)
+
+	if stderr.Len() > 0 {
+		result.StderrFile = saveStderr(stderr.Bytes(), result, dir, logs)
+	}
+	result.Diagnostics = parseDiagnostics(stderr.String())
+	if !result.Passed {
+		if timedOut {
+			result.Category = timeoutFailure
+		} else if result.ExitCode == tool.ExitCode && !outputMatched {
+			result.Category = outputFailure
+		} else {
+			result.Category = classifyFailure(result.Diagnostics, stderr.String(), result.Signal)
+		}
+	}
+
+	if result.ExitCode == 0 && len(familyName) > 0 && (phase == compilePhase || phase == linkPhase) {
+		output := phaseOutput(familyName, t, phase)
+		if testSet.AnalyzeBinaries {
+			result.Binary = analyzeBinary(filepath.Join(dir, output), output, t.entry.TestName, t.count)
+			if result.Binary != nil && len(result.Binary.SymbolCheck) > 0 && result.Passed {
+				result.Passed = false
+				result.Category = symbolFailure
+			}
+		}
+		if debugInfo {
+			result.DWARF = analyzeDebugInfo(filepath.Join(dir, output), t.entry.TestName, t.count)
+			if result.DWARF != nil && len(result.DWARF.DepthCheck) > 0 && result.Passed {
+				result.Passed = false
+				result.Category = debugInfoFailure
+			}
+		}
+	}
+	return result
+}
+
+// the context of one build of a test, expiring with the timeout of the test set if there is one
+func buildContext() (context.Context, context.CancelFunc) {
+	if testSet.Timeout > 0 {
+		return context.WithTimeout(context.Background(), time.Duration(testSet.Timeout)*time.Second)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// runs one process in dir, killing it when the context of the build expires. Returns its state (nil if it could not
+// be started), its wall time and whether it timed out
+func runStep(ctx context.Context, args []string, dir string, stdout, stderr *bytes.Buffer) (*os.ProcessState, time.Duration, bool, error) {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	killProcessGroupOnCancel(cmd)
+
+	start := time.Now()
+	err := cmd.Run()
+	return cmd.ProcessState, time.Since(start), ctx.Err() == context.DeadlineExceeded, err
+}
+
+// saves the stderr of a tool into the logs directory of dir, returns the name of the file relative to dir
+func saveStderr(stderr []byte, result TestResult, dir, logs string) string {
+	check(os.MkdirAll(filepath.Join(dir, logs), os.ModePerm))
+
+	name := filepath.Join(logs, result.fileName()+".stderr")
+	check(os.WriteFile(filepath.Join(dir, name), stderr, 0644))
+	return name
+}
+
+// the variants of the run of a compiler in the phase: without debug information, and with it if requested and the
+// phase produces an object file
+func debugVariants(familyName, phase string) []bool {
+	if testSet.DebugInfo && len(familyName) > 0 && (phase == compilePhase || phase == linkPhase) {
+		return []bool{false, true}
+	}
+	return []bool{false}
+}
+
+// the suffix of the messages of the runs with debug information
+func debugName(debugInfo bool) string {
+	if debugInfo {
+		return " -g"
+	}
+	return ""
+}
+
+// the name of the files belonging to the result, unique within a run
+func (r *TestResult) fileName() string {
+	name := fileNameSafe(r.Tool) + "-" + r.Test + "-" + r.Count + "-" + r.Mode
+	if len(r.Phase) > 0 {
+		name += "-" + r.Phase
+	}
+	if r.Debug {
+		name += "-g"
+	}
+	if len(r.Std) > 0 {
+		name += "-" + fileNameSafe(r.Std)
+	}
+	return name
+}
+
+// replaces the characters of s which could cause problems in a file name
+func fileNameSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>| `, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// runs all the tools on all the generated tests located in dir, and writes the results file and the report
+func runTests(tests []generatedTest, dir string, manifest *Manifest) RunResults {
+	run := RunResults{SetName: testSet.SetName, Compilers: make([]CompilerIdentity, 0), Results: make([]TestResult, 0)}
+
+	identities := probeCompilers()
+	for _, tool := range tools() {
+		if identity, ok := identities[tool.Name]; ok {
+			fmt.Println("Compiler:", tool.Name, identity.Family, identity.Version, identity.Target, identity.DefaultStd)
+			run.Compilers = append(run.Compilers, identity)
+		}
+	}
+
+	for _, mode := range limitModes() {
+		if mode != defaultLimits && mode != raisedLimits {
+			panic("unknown limit mode: " + mode)
+		}
+
+		for _, tool := range tools() {
+			identity, ok := identities[tool.Name]
+			_, familyName := toolCompiler(tool)
+			var history []historyRecord
+			if ok {
+				history = loadHistory(historyKey(tool.Name, &identity))
+			} else {
+				history = loadHistory(historyKey(tool.Name, nil))
+			}
+
+			for _, t := range tests {
+				// with raised limits only the tests whose limits can be raised are run again
+				if mode == raisedLimits && len(raisingFlags(familyName, t.entry.TestName, t.count)) == 0 {
+					continue
+				}
+
+				// the tools which are not compilers have no phases and no standards
+				phases, stds := []string{""}, []string{""}
+				if len(familyName) > 0 {
+					phases, stds = testPhases(t.entry), standards()
+				}
+
+				for _, std := range stds {
+					// a test needing a newer standard (or one the compiler cannot select) is not run, rather than failing to
+					// compile
+					var compiler *CompilerIdentity
+					if ok {
+						compiler = &identity
+					}
+					reason := unselectableStandard(familyName, std)
+					if len(reason) == 0 {
+						reason = notApplicable(t.entry.TestName, effectiveStandard(familyName, t.entry, std, compiler))
+					}
+					if len(familyName) > 0 && len(reason) > 0 {
+						fmt.Println("Not applicable:", tool.Name, t.name+standardName(std), reason)
+						for _, phase := range phases {
+							run.Results = append(run.Results, TestResult{Tool: tool.Name, Test: t.entry.TestName, Count: t.count,
+								Mode: mode, Phase: phase, Std: std, Compiler: compiler, Skipped: reason})
+						}
+						continue
+					}
+
+					for _, phase := range phases {
+						for _, debugInfo := range debugVariants(familyName, phase) {
+							predictedTime, predictedMemory := predictCost(history, t.entry.TestName, mode, phase, std, debugInfo, t.count)
+							reason, warning := checkPredictedCost(t.name, predictedTime, predictedMemory)
+							predictedTime, predictedMemory = finitePrediction(predictedTime), finitePrediction(predictedMemory)
+							if len(reason) > 0 {
+								run.Results = append(run.Results, TestResult{Tool: tool.Name, Test: t.entry.TestName, Count: t.count,
+									Mode: mode, Phase: phase, Debug: debugInfo, Std: std, Compiler: compiler,
+									PredictedTime: predictedTime, PredictedMemory: predictedMemory, Skipped: reason})
+								continue
+							}
+
+							fmt.Println("Testing:", tool.Name, t.name, mode, phase+debugName(debugInfo)+standardName(std), time.Now().Format(time.RFC3339Nano))
+							result := runTool(tool, t, mode, phase, std, debugInfo, dir, "logs")
+							result.Compiler = compiler
+							result.PredictedTime, result.PredictedMemory = predictedTime, predictedMemory
+							result.Warning = warning
+
+							verdict := "passed"
+							if !result.Passed {
+								verdict = "FAILED (" + result.Category + ")"
+								if e := firstError(result.Diagnostics); len(e) > 0 {
+									verdict += ": " + e
+								}
+							}
+							fmt.Printf("%s %s%s%s %s (exit code %d, %.3fs, %d KB)\n", t.name, phase, debugName(debugInfo), standardName(std), verdict, result.ExitCode, result.WallTime, result.MaxRSS)
+
+							if result.Category == iceFailure {
+								result.Reproducer = createReproducer(tool, t, result, dir)
+								fmt.Println("Reproducer:", result.Reproducer)
+							}
+							run.Results = append(run.Results, result)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	run.Scaling = fitScaling(run.Results)
+	for _, fit := range run.Scaling {
+		if fit.Superlinear {
+			fmt.Printf("Scaling: %s %s %s grows %s with the count\n", fit.Tool, fit.Test, fit.Metric, fit.Model)
+		}
+	}
+
+	manifest.Compilers = run.Compilers
+	writeManifest(manifest, dir)
+	writeResults(run, dir)
+	writeReport(run, dir)
+	appendHistory(run, manifest)
+	return run
+}
+
+// writes the results in dir, in the format requested by resultFormat
+func writeResults(run RunResults, dir string) {
+	var content []byte
+	var err error
+	extension := strings.ToLower(testSet.ResultFormat)
+
+	switch testSet.ResultFormat {
+	case "XML":
+		content, err = xml.MarshalIndent(run, "", "  ")
+	case "JSON":
+		content, err = json.MarshalIndent(run, "", "  ")
+	default:
+		var buffer bytes.Buffer
+		w := csv.NewWriter(&buffer)
+		w.Write(csvHeader)
+		for i := range run.Results {
+			w.Write(run.Results[i].csvRecord())
+		}
+		w.Flush()
+		content, err = buffer.Bytes(), w.Error()
+		extension = "csv"
+	}
+	check(err)
+
+	f, err := os.Create(filepath.Join(dir, "results."+extension))
+	check(err)
+	defer f.Close()
+
+	f.Write(content)
+}
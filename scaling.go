@@ -0,0 +1,200 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// the complexity of a test for a tool, as fitted against the counts of a sweep
+type ScalingFit struct {
+	Tool   string  `json:"tool" xml:"tool,attr"`
+	Test   string  `json:"test" xml:"test,attr"`
+	Mode   string  `json:"mode" xml:"mode,attr"`
+	Phase  string  `json:"phase,omitempty" xml:"phase,attr,omitempty"`
+	Debug  bool    `json:"debugInfo,omitempty" xml:"debugInfo,attr,omitempty"`
+	Std    string  `json:"std,omitempty" xml:"std,attr,omitempty"`
+	Metric string  `json:"metric" xml:"metric,attr"` // time (seconds) or memory (kilobytes)
+	Model  string  `json:"model" xml:"model,attr"`
+	A      float64 `json:"a" xml:"a,attr"` // the parameters of the model, see scalingModels
+	B      float64 `json:"b" xml:"b,attr"`
+	R2     float64 `json:"r2" xml:"r2,attr"`
+	Points int     `json:"points" xml:"points,attr"`
+
+	Superlinear bool `json:"superlinear" xml:"superlinear,attr"` // the growth is worse than linear
+}
+
+// a model the measurements are fitted to, in the form a + b * f(n), except the exponential one being a * e^(b * n)
+type scalingModel struct {
+	name        string
+	f           func(n float64) float64
+	superlinear bool
+}
+
+// the models, from the mildest to the steepest one
+var scalingModels = []scalingModel{
+	{"linear", func(n float64) float64 { return n }, false},
+	{"n log n", func(n float64) float64 { return n * math.Log2(n) }, true},
+	{"quadratic", func(n float64) float64 { return n * n }, true},
+	{"exponential", nil, true},
+}
+
+// a steeper model is only chosen if it lowers the squared error of the milder one by this ratio at least, so the
+// noise of the measurements does not make linear tests look quadratic
+const steeperModelGain = 0.9
+
+// the minimum number of different counts needed for fitting
+const minimumScalingPoints = 3
+
+// fits the time and memory of the successful results against the count, for each tool, test, mode, phase (with
+// and without debug information) and standard having results for enough different counts
+func fitScaling(results []TestResult) []ScalingFit {
+	type key struct {
+		tool, test, mode, phase, std string
+		debugInfo                    bool
+	}
+	groups := make(map[key][]TestResult)
+	keys := make([]key, 0)
+	for _, r := range results {
+		if !r.Passed {
+			continue
+		}
+		k := key{r.Tool, r.Test, r.Mode, r.Phase, r.Std, r.Debug}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+
+	fits := make([]ScalingFit, 0)
+	for _, k := range keys {
+		ns, times, memories := make([]float64, 0), make([]float64, 0), make([]float64, 0)
+		counts := make(map[float64]bool)
+		for _, r := range groups[k] {
+			n, err := strconv.ParseFloat(r.Count, 64)
+			if err != nil || n < 1 {
+				continue
+			}
+			counts[n] = true
+			ns = append(ns, n)
+			times = append(times, r.WallTime)
+			memories = append(memories, float64(r.MaxRSS))
+		}
+		if len(counts) < minimumScalingPoints {
+			continue
+		}
+
+		for _, metric := range []struct {
+			name   string
+			values []float64
+		}{{"time", times}, {"memory", memories}} {
+			fit, ok := bestFit(ns, metric.values)
+			if !ok {
+				continue
+			}
+			fit.Tool, fit.Test, fit.Mode, fit.Phase, fit.Debug, fit.Metric = k.tool, k.test, k.mode, k.phase, k.debugInfo, metric.name
+			fit.Std = k.std
+			fits = append(fits, fit)
+		}
+	}
+
+	sort.SliceStable(fits, func(i, j int) bool {
+		if fits[i].Test != fits[j].Test {
+			return fits[i].Test < fits[j].Test
+		}
+		return fits[i].Tool < fits[j].Tool
+	})
+	return fits
+}
+
+// fits the values against the counts with all the models, returns the best fitting one
+func bestFit(ns, ys []float64) (ScalingFit, bool) {
+	var best ScalingFit
+	bestError := math.Inf(1)
+	found := false
+	for _, model := range scalingModels {
+		a, b, ok := fitModel(model, ns, ys)
+		if !ok || b <= 0 {
+			continue
+		}
+		squaredError := 0.0
+		for i := range ns {
+			d := ys[i] - predict(model, a, b, ns[i])
+			squaredError += d * d
+		}
+		if !found || squaredError < bestError*steeperModelGain {
+			best = ScalingFit{Model: model.name, A: a, B: b, R2: rSquared(ys, squaredError), Points: len(ns),
+				Superlinear: model.superlinear}
+			bestError = squaredError
+			found = true
+		}
+	}
+	return best, found
+}
+
+// the least squares fit of the model, false if the model cannot be fitted to the values
+func fitModel(model scalingModel, ns, ys []float64) (float64, float64, bool) {
+	xs := make([]float64, len(ns))
+	zs := make([]float64, len(ys))
+	for i := range ns {
+		if model.f == nil {
+			// exponential: ln y = ln a + b * n
+			if ys[i] <= 0 {
+				return 0, 0, false
+			}
+			xs[i], zs[i] = ns[i], math.Log(ys[i])
+		} else {
+			xs[i], zs[i] = model.f(ns[i]), ys[i]
+		}
+	}
+
+	a, b, ok := linearRegression(xs, zs)
+	if ok && model.f == nil {
+		a = math.Exp(a)
+	}
+	return a, b, ok
+}
+
+// the value of the model with the parameters at n
+func predict(model scalingModel, a, b, n float64) float64 {
+	if model.f == nil {
+		return a * math.Exp(b*n)
+	}
+	return a + b*model.f(n)
+}
+
+// the least squares fit of z = a + b * x
+func linearRegression(xs, zs []float64) (float64, float64, bool) {
+	n := float64(len(xs))
+	var sx, sz, sxx, sxz float64
+	for i := range xs {
+		sx += xs[i]
+		sz += zs[i]
+		sxx += xs[i] * xs[i]
+		sxz += xs[i] * zs[i]
+	}
+	d := n*sxx - sx*sx
+	if d == 0 || math.IsInf(d, 0) || math.IsNaN(d) {
+		return 0, 0, false
+	}
+	b := (n*sxz - sx*sz) / d
+	return (sz - b*sx) / n, b, true
+}
+
+// the coefficient of determination of a fit with the given squared error
+func rSquared(ys []float64, squaredError float64) float64 {
+	mean := 0.0
+	for _, y := range ys {
+		mean += y
+	}
+	mean /= float64(len(ys))
+
+	total := 0.0
+	for _, y := range ys {
+		total += (y - mean) * (y - mean)
+	}
+	if total == 0 {
+		return 1
+	}
+	return 1 - squaredError/total
+}
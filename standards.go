@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+)
+
+// the standards of each language, oldest first, named as in -std
+var standardOrder = map[string][]string{
+	cppLanguage: {"c++98", "c++11", "c++14", "c++17", "c++20", "c++23"},
+	cLanguage:   {"c89", "c99", "c11", "c17", "c23"},
+}
+
+// the other names of the standards accepted by the compilers
+var standardAliases = map[string]string{
+	"c++03": "c++98", "c++0x": "c++11", "c++1y": "c++14", "c++1z": "c++17", "c++2a": "c++20", "c++2b": "c++23",
+	"c++latest": "c++23", "c90": "c89", "iso9899:1990": "c89", "c9x": "c99", "c1x": "c11", "c18": "c17", "c2x": "c23",
+}
+
+// the oldest standard each test can be compiled with in each language, the tests not listed here can be compiled
+// with the oldest standard of the language
+var minimumStandards = map[string]map[string]string{
+	cppLanguage: {
+		"pointerAndArrayDeclaratorsModifyingSomething":     "c++11",
+		"structuredBindingsInOneDeclaration":               "c++17",
+		"sizeOfAnObject":                                   "c++11",
+		"nonStaticDataMembersOfClass":                      "c++11",
+		"lambdaCapturesInOneLambdaExpression":              "c++11",
+		"nestingOfClasses":                                 "c++11",
+		"functionsRegisteredByat_quick_exit":               "c++11",
+		"classMembersDeclaredInASingleMemberSpecification": "c++11",
+		"finalOverridingVirtualFunctions":                  "c++11",
+		"friendsOfAClass":                                  "c++11",
+		"accessControlDeclarationsInClass":                 "c++11",
+		"recursiveConstexpr":                               "c++11",
+		"constexprEvaluationSteps":                         "c++11",
+		"constexprLoopIterations":                          "c++14",
+		"constexprAllocationSize":                          "c++20",
+		"constevalCallDepth":                               "c++20",
+		"elementsInPackExpansion":                          "c++14",
+		"operandsInFoldExpression":                         "c++17",
+		"nestedPackExpansions":                             "c++17",
+		"typesInSizeofPack":                                "c++14",
+		"elementsInTuple":                                  "c++11",
+		"alternativesInVariant":                            "c++17",
+		"handlersPerTryBlock":                              "c++11",
+		"numberOfPlaceholders":                             "c++11",
+		"virtualFunctionsInTranslationUnits":               "c++11",
+		"conjunctionsInOneRequiresClause":                  "c++20",
+		"nestingOfRequiresExpressions":                     "c++20",
+		"conceptsSubsumedInOverloadResolution":             "c++20",
+		"constrainedOverloadsInOneOverloadSet":             "c++20",
+		"moduleInterfacesImportedByOneTranslationUnit":     "c++20",
+		"partitionsInOneModule":                            "c++20",
+		"exportedDeclarationsInOneModule":                  "c++20",
+		"evalMacroRecursionDepth":                          "c++11",
+		"argumentsInVariadicMacro":                         "c++11",
+		"nestingOfVaOptExpansions":                         "c++20",
+	},
+	cLanguage: {
+		"caseLabelsForSwitch":      "c99",
+		"evalMacroRecursionDepth":  "c99",
+		"argumentsInVariadicMacro": "c99",
+		"nestingOfVaOptExpansions": "c23",
+	},
+}
+
+// the standards the tests are compiled with one after the other, by default only the one selected by the flags
+func standards() []string {
+	if len(testSet.Standards) > 0 {
+		return testSet.Standards
+	}
+	return []string{""}
+}
+
+// the suffix of the messages of the runs with a standard of the sweep
+func standardName(std string) string {
+	if len(std) > 0 {
+		return " " + std
+	}
+	return ""
+}
+
+// the name of the standard in standardOrder, without the GNU extensions and the aliases, ie. c++17 for gnu++1z
+func normalizeStandard(std string) string {
+	std = strings.ToLower(std)
+	if strings.HasPrefix(std, "gnu") {
+		std = "c" + strings.TrimPrefix(std, "gnu")
+	}
+	if alias, ok := standardAliases[std]; ok {
+		return alias
+	}
+	return std
+}
+
+// the position of the standard among the ones of the language of the test set, -1 if it is unknown
+func standardIndex(std string) int {
+	std = normalizeStandard(std)
+	for i, s := range standardOrder[language()] {
+		if s == std {
+			return i
+		}
+	}
+	return -1
+}
+
+// the values of /std accepted by msvc for the standards, the others cannot be selected and msvc falls back to its
+// default one with a warning
+var msvcStandards = map[string]string{
+	"c++14": "c++14", "c++17": "c++17", "c++20": "c++20", "c++23": "c++latest", "c11": "c11", "c17": "c17",
+}
+
+// the flag selecting the standard for a compiler of the family, empty for the standard selected by the flags
+func standardFlag(familyName, std string) string {
+	switch {
+	case len(std) == 0 || len(familyName) == 0:
+		return ""
+	case familyName == "msvc":
+		return "/std:" + msvcStandards[normalizeStandard(std)]
+	}
+	return "-std=" + std
+}
+
+// why a compiler of the family cannot compile with the standard of the sweep, or an empty string if it can
+func unselectableStandard(familyName, std string) string {
+	if _, ok := msvcStandards[normalizeStandard(std)]; familyName == "msvc" && len(std) > 0 && !ok {
+		return "not applicable, msvc cannot select " + std
+	}
+	return ""
+}
+
+// the standard selected by the last of the flags selecting one, empty if there is none
+func flagStandard(flags string) string {
+	std := ""
+	for _, flag := range strings.Fields(flags) {
+		switch {
+		case strings.HasPrefix(flag, "-std="):
+			std = strings.TrimPrefix(flag, "-std=")
+		case strings.HasPrefix(flag, "/std:"):
+			std = strings.TrimPrefix(flag, "/std:")
+		case flag == "-ansi":
+			std = standardOrder[language()][0]
+		}
+	}
+	return std
+}
+
+// the standard the test is compiled with by a compiler of the family: the one of the sweep, the one selected by the
+// flags, or the default one of the compiler (nil if it is unknown)
+func effectiveStandard(familyName string, entry *TestEntry, std string, identity *CompilerIdentity) string {
+	if len(std) > 0 {
+		return std
+	}
+	if std := flagStandard(familyFlagsOf(familyName) + " " + entry.CompilerFlags); len(std) > 0 {
+		return std
+	}
+	if identity != nil {
+		return identity.DefaultStd
+	}
+	return ""
+}
+
+// why the test cannot be compiled with the standard, or an empty string if it can or the standard is unknown
+func notApplicable(testName, std string) string {
+	minimum, ok := minimumStandards[language()][testName]
+	if !ok {
+		return ""
+	}
+	if i := standardIndex(std); i >= 0 && i < standardIndex(minimum) {
+		return "not applicable, needs " + minimum
+	}
+	return ""
+}
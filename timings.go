@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// where the compiler spent its time, in seconds. The parts are measured by the compiler itself, so their sum can
+// differ from the wall time of the process
+type CompileTimings struct {
+	Preprocessing float64 `json:"preprocessing" xml:"preprocessing,attr"`
+	Parsing       float64 `json:"parsing" xml:"parsing,attr"` // including the semantic analysis
+	Templates     float64 `json:"templates" xml:"templates,attr"`
+	Constexpr     float64 `json:"constexpr" xml:"constexpr,attr"`
+	Optimization  float64 `json:"optimization" xml:"optimization,attr"`
+	Codegen       float64 `json:"codegen" xml:"codegen,attr"`
+}
+
+// the parts of the timings with their names, in the order they are shown
+func (t *CompileTimings) Parts() []timingPart {
+	return []timingPart{
+		{"preprocessing", t.Preprocessing}, {"parsing", t.Parsing}, {"templates", t.Templates},
+		{"constexpr", t.Constexpr}, {"optimization", t.Optimization}, {"codegen", t.Codegen},
+	}
+}
+
+// the sum of the timings of two compilations, nil if there are none
+func (t *CompileTimings) add(other *CompileTimings) *CompileTimings {
+	if t == nil {
+		return other
+	}
+	if other == nil {
+		return t
+	}
+	return &CompileTimings{
+		Preprocessing: t.Preprocessing + other.Preprocessing,
+		Parsing:       t.Parsing + other.Parsing,
+		Templates:     t.Templates + other.Templates,
+		Constexpr:     t.Constexpr + other.Constexpr,
+		Optimization:  t.Optimization + other.Optimization,
+		Codegen:       t.Codegen + other.Codegen,
+	}
+}
+
+// one part of the timings, as shown in the report
+type timingPart struct {
+	Name    string
+	Seconds float64
+}
+
+// the sum of the parts
+func (t *CompileTimings) Total() float64 {
+	total := 0.0
+	for _, p := range t.Parts() {
+		total += p.Seconds
+	}
+	return total
+}
+
+var (
+	// a line of the table printed by gcc with -ftime-report, ie.
+	// " template instantiation             :   0.08 ( 24%)   0.07 ( 39%)   0.14 ( 26%)    11M ( 27%)"
+	gccTimeVariable = regexp.MustCompile(`^ ([^|:][^:]*?)\s*:\s*[0-9.]+ \(\s*\d+%\)\s+[0-9.]+ \(\s*\d+%\)\s+([0-9.]+) \(`)
+	// the passes of gcc generating the machine code, all the other passes of the last phase being optimizations
+	gccCodegenVariable = regexp.MustCompile(`^(expand|final|integrated RA|LRA |reload|scheduling|symout|variable output|thread pro- & epilogue|shorten branches|machine dep reorg|peephole 2|reg stack|register information|rest of compilation|initialize rtl)`)
+)
+
+// the flags making the compiler of the family measure its phases, empty if it cannot. The trace of clang is written
+// into traceFile
+func timeReportFlags(familyName, traceFile string) []string {
+	switch familyName {
+	case "gcc":
+		return []string{"-ftime-report"}
+	case "clang":
+		return []string{"-ftime-trace=" + traceFile, "-ftime-trace-granularity=0"}
+	}
+	return nil
+}
+
+// the timings reported by the compiler of the family, nil if there are none
+func parseTimings(familyName, stderr, traceFile string) *CompileTimings {
+	switch familyName {
+	case "gcc":
+		return parseTimeReport(stderr)
+	case "clang":
+		content, err := os.ReadFile(traceFile)
+		if err != nil {
+			return nil
+		}
+		return parseTimeTrace(content)
+	}
+	return nil
+}
+
+// the timings in the -ftime-report table of gcc, using the wall times
+func parseTimeReport(stderr string) *CompileTimings {
+	variables := make(map[string]float64)
+	codegen := 0.0
+	for _, line := range strings.Split(stderr, "\n") {
+		m := gccTimeVariable.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		wall, err := strconv.ParseFloat(m[2], 64)
+		check(err)
+		variables[m[1]] = wall
+		if gccCodegenVariable.MatchString(m[1]) {
+			codegen += wall
+		}
+	}
+	if len(variables) == 0 {
+		return nil
+	}
+
+	// the variables of the front end are parts of the parsing phases
+	t := &CompileTimings{
+		Preprocessing: variables["preprocessing"],
+		Templates:     variables["template instantiation"],
+		Constexpr:     variables["constant expression evaluation"],
+		Codegen:       codegen,
+	}
+	t.Parsing = positive(variables["phase parsing"] + variables["phase lang. deferred"] - t.Preprocessing - t.Templates - t.Constexpr)
+	t.Optimization = positive(variables["phase opt and generate"] - codegen)
+	return t
+}
+
+// an event of the chrome trace written by clang with -ftime-trace
+type traceEvent struct {
+	Name     string  `json:"name"`
+	Phase    string  `json:"ph"`
+	Duration float64 `json:"dur"` // in microseconds
+}
+
+// the timings in the -ftime-trace file of clang, from the totals of its events
+func parseTimeTrace(content []byte) *CompileTimings {
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if json.Unmarshal(content, &trace) != nil {
+		return nil
+	}
+
+	totals := make(map[string]float64)
+	for _, e := range trace.TraceEvents {
+		if e.Phase == "X" && strings.HasPrefix(e.Name, "Total ") {
+			totals[strings.TrimPrefix(e.Name, "Total ")] += e.Duration / 1e6
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+
+	t := &CompileTimings{
+		Preprocessing: totals["PreprocessorDirective"] + totals["Lex"],
+		Templates:     totals["InstantiateClass"] + totals["InstantiateFunction"],
+		Constexpr:     totals["EvaluateAsConstantExpr"] + totals["EvaluateAsRValue"] + totals["EvaluateAsInitializer"] + totals["EvaluateForOverflow"],
+		Optimization:  totals["Optimizer"] + totals["OptModule"],
+		Codegen:       totals["CodeGenPasses"] + totals["CodeGen Function"],
+	}
+	t.Parsing = positive(totals["Frontend"] - t.Preprocessing - t.Templates - t.Constexpr)
+	return t
+}
+
+// the trace file of the result, relative to the directory of the test set
+func traceFileName(result TestResult) string {
+	return filepath.Join("traces", result.fileName()+".json")
+}
+
+// x, or zero if it is negative because the parts measured by the compiler overlap
+func positive(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	return x
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
-	"fmt"
+	"hash/fnv"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -33,11 +33,27 @@ var cppPrimitiveTypes = [...]cppType{
 
 // These structures represent a test set that is being loaded from th json file
 type TestEntry struct {
-	TestName    string   `json:"testName"`
-	Count       []string `json:"count"`
-	Minimum     string   `json:"minimum"`
-	Run         bool     `json:"run"`
-	Description string   `json:"desription"`
+	TestName      string   `json:"testName"`
+	Count         []string `json:"count"`
+	Minimum       string   `json:"minimum"`
+	Run           bool     `json:"run"`
+	Description   string   `json:"description"`
+	CompilerFlags string   `json:"compilerFlags"`
+	Phases        []string `json:"phases"` // the phases the test is run in, instead of the ones of the test set
+}
+
+// a tool the generated tests are run with. The command can contain the {compiler}, {flags}, {source}, {output},
+// {includeDir}, {count} and {test} placeholders, the tool succeeds if it exits with the exit code and its output
+// matches the regular expression (if given). A tool can use a different compiler than the one of the test set
+type ToolEntry struct {
+	Name           string `json:"name"`
+	Command        string `json:"command"`
+	ExitCode       int    `json:"exitCode"`
+	StdoutRegex    string `json:"stdoutRegex"`
+	Compiler       string `json:"compiler"`
+	CompilerFamily string `json:"compilerFamily"`
+
+	stdoutRegex *regexp.Regexp // StdoutRegex compiled when the test set is loaded, nil if there is none
 }
 
 // represents a test set as loaded from the json file
@@ -46,15 +62,39 @@ type TestSet struct {
 	RandomBehaviour       bool        `json:"randomBehaviour"`
 	GenerateMakefile      bool        `json:"generateMakefile"`
 	GenerateCMakeListsTxt bool        `json:"generateCMakeListsTxt"`
+	GenerateCompileCmds   bool        `json:"generateCompileCommands"`
 	CompilerFlags         string      `json:"compilerFlags"`
 	CompilationTimes      int         `json:"compilationTimes"`
 	TimeFlags             string      `json:"timeFlags"`
 	TimedCompilation      bool        `json:"timedCompilation"`
 	ResultFormat          string      `json:"resultFormat"`
 	Compiler              string      `json:"compiler"`
+	CompilerFamily        string      `json:"compilerFamily"`
+	Tools                 []ToolEntry `json:"tools"`
+	LimitModes            []string    `json:"limitModes"`
+	Timeout               int         `json:"timeout"`       // in seconds, 0 for no timeout
+	MemoryLimit           int         `json:"memoryLimit"`   // in megabytes, 0 for no limit
+	OverrunPolicy         string      `json:"overrunPolicy"` // warn or refuse to run the tests predicted to overrun
+	HistoryDir            string      `json:"historyDir"`
+	Seed                  int64       `json:"seed"` // of the random generators, a new one for each run if 0
+	Phases                []string    `json:"phases"`
+	TimeReport            bool        `json:"timeReport"`      // measure the phases with -ftime-report or -ftime-trace
+	AnalyzeBinaries       bool        `json:"analyzeBinaries"` // inspect the object files and the executables
+	DebugInfo             bool        `json:"debugInfo"`       // compile and link once more with debug information
+	Language              string      `json:"language"`        // c++ (the default) or c
+	Standards             []string    `json:"standards"`       // the -std values the tests are compiled with
 	Tests                 []TestEntry `json:"tests"`
 }
 
+// a source file generated by one of the tests for one of its counts
+type generatedTest struct {
+	name     string // the name of the test with the count appended, used as the target name
+	entry    *TestEntry
+	count    string
+	fileName string // relative to the directory of the test set
+	units    []translationUnit
+}
+
 // a binary tree structure, for some of the tests that generate a class hierarchy
 type treeNode struct {
 	left  *treeNode
@@ -65,6 +105,17 @@ type treeNode struct {
 // this is the actual test set object
 var testSet TestSet
 
+// the source of the random values of the generators, seeded for each generated test
+var random = rand.New(rand.NewSource(1))
+
+// seeds the generators for the test with the seed of the test set, so the source of each test is the same whatever
+// the other tests are, and can be generated again from the seed in the manifest
+func seedGenerator(name string) {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	random = rand.New(rand.NewSource(testSet.Seed ^ int64(h.Sum64())))
+}
+
 // some constants
 const iostream = "#include <iostream>\n\n"
 
@@ -73,9 +124,10 @@ const iostream = "#include <iostream>\n\n"
 //
 func trace() string {
 	pc := make([]uintptr, 10) // at least 1 entry needed
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[0])
-	var fn = f.Name()
+	n := runtime.Callers(2, pc)
+	// the frames know about the inlined functions, the caller is not mistaken for a function inlined into it
+	frame, _ := runtime.CallersFrames(pc[:n]).Next()
+	var fn = frame.Function
 	var dotIndex = strings.Index(fn, ".")
 	if dotIndex != -1 {
 		fn = fn[dotIndex+1:]
@@ -83,6 +135,26 @@ func trace() string {
 	return fn
 }
 
+// the clause of Annex B the test belongs to, as found at the beginning of its description, ie. "2.1"
+func annexClause(description string) string {
+	if !strings.HasPrefix(description, "(") {
+		return ""
+	}
+	end := strings.Index(description, ")")
+	if end == -1 {
+		return ""
+	}
+	return description[1:end]
+}
+
+// the extra compiler flags of a test, followed by a space if there are any
+func testFlags(entry TestEntry) string {
+	if len(entry.CompilerFlags) > 0 {
+		return entry.CompilerFlags + " "
+	}
+	return ""
+}
+
 func check(e error) {
 	if e != nil {
 		panic(e)
@@ -92,7 +164,7 @@ func check(e error) {
 func oneAsType(idx int) string {
 	var num int
 	if testSet.RandomBehaviour {
-		num = rand.Intn(cppPrimitiveTypes[idx].maxValue-cppPrimitiveTypes[idx].minValue) + cppPrimitiveTypes[idx].minValue
+		num = random.Intn(cppPrimitiveTypes[idx].maxValue-cppPrimitiveTypes[idx].minValue) + cppPrimitiveTypes[idx].minValue
 	} else {
 		num = 1
 	}
@@ -103,9 +175,15 @@ func oneAsType(idx int) string {
 	return strconv.Itoa(num)
 }
 
+// the directory the tests of the test set are generated into
+func testSetDir() string {
+	dir, _ := os.Getwd()
+	return dir + "/" + testSet.SetName
+}
+
 func getFileName(fn string, count string) string {
 	dir, _ := os.Getwd()
-	return dir + "/" + testSet.SetName + "/" + fn + "-" + count + ".cpp"
+	return dir + "/" + testSet.SetName + "/" + fn + "-" + count + sourceExtension()
 }
 
 func writeTestFile(funName, count, content string) string {
@@ -118,6 +196,13 @@ func writeTestFile(funName, count, content string) string {
 	return fileName
 }
 
+// a main printing the sum of the values returned by the functions, called through an array so that the size of
+// the expressions does not grow with their number
+func mainSummingFunctions(functions []string) string {
+	return "\nint (*const functions[])() = {" + strings.Join(functions, ", ") + "};\n\n" +
+		"int main() {\n\tint sum = 0;\n\tfor (unsigned i = 0; i < sizeof functions / sizeof *functions; i++)\n\t\tsum += functions[i]();\n\tstd::cout << sum << std::endl;\n}\n"
+}
+
 func repeat(what string, times int) string {
 	result := ""
 	for i := 0; i < times; i++ {
@@ -127,7 +212,7 @@ func repeat(what string, times int) string {
 }
 
 func atexitHelper(count, funcname string) string {
-	content := iostream
+	content := iostream + "#include <cstdlib>\n"
 	requiredCount, _ := strconv.Atoi(count)
 	for i := 1; i <= requiredCount; i++ {
 		content += "\nvoid handler" + strconv.Itoa(i) + "() {\n\tstd::cout << \".\"  "
@@ -256,9 +341,15 @@ func generateClassHierarchyWitClasses(count string, virtual bool, testname strin
 	return writeTestFile(testname, count, iostream+classContent+mainContent)
 }
 
-func writeHeaderFile(count int, content string) {
+// writes the header with the given prefix and number into the include directory of the test set
+func writeHeaderFile(prefix string, count int, content string) {
+	writeIncludeFile(prefix+strconv.Itoa(count)+".h", content)
+}
+
+// writes a header with the given name into the include directory of the test set
+func writeIncludeFile(name, content string) {
 	dir, _ := os.Getwd()
-	fileName := dir + "/" + testSet.SetName + "/inc/header" + strconv.Itoa(count) + ".h"
+	fileName := dir + "/" + testSet.SetName + "/inc/" + name
 	filePath, _ := filepath.Abs(fileName)
 	path := filepath.Dir(filePath)
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -271,27 +362,3 @@ func writeHeaderFile(count int, content string) {
 
 	f.WriteString(content)
 }
-
-func Start(compiler string, args ...string) (p *os.Process, err error) {
-	if compiler, err = exec.LookPath(compiler); err == nil {
-		var procAttr os.ProcAttr
-		procAttr.Files = []*os.File{os.Stdin,
-			os.Stdout, os.Stderr}
-		p, err := os.StartProcess(compiler, args, &procAttr)
-		if err == nil {
-			return p, nil
-		}
-	}
-	return nil, err
-}
-
-func compile(args ...string) bool {
-
-	fmt.Println(args, " ")
-	if proc, err := Start(testSet.Compiler, args...); err == nil {
-		proc.Wait()
-		return true
-	}
-	fmt.Println("bad bad ")
-	return false
-}